@@ -0,0 +1,17 @@
+package gen
+
+import (
+	"github.com/DustyRat/post-it/internal/options"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCmdGen ...
+func NewCmdGen(opts *options.Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gen",
+		Short: "Generate URL templates and input file skeletons.",
+	}
+	cmd.AddCommand(NewCmdOpenAPI(opts))
+	return cmd
+}
@@ -0,0 +1,45 @@
+package gen
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+
+	"github.com/DustyRat/post-it/internal/openapi"
+	"github.com/DustyRat/post-it/internal/options"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCmdOpenAPI ...
+func NewCmdOpenAPI(opts *options.Options) *cobra.Command {
+	var rows int
+	cmd := &cobra.Command{
+		Use:   "openapi <spec> <operationId>",
+		Args:  cobra.ExactArgs(2),
+		Short: "Print the URL template of an OpenAPI operation to stderr and an input file skeleton to stdout.",
+		Example: `post-it gen openapi spec.yaml getOrder > input.csv
+post-it gen openapi spec.json createOrder -n 10 > input.csv`,
+		Run: func(cmd *cobra.Command, args []string) {
+			spec, err := openapi.Load(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+			operation, err := spec.Operation(args[1])
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			fmt.Fprintf(cmd.ErrOrStderr(), "post-it %s %q\n", operation.Method, spec.Template(operation))
+
+			w := csv.NewWriter(cmd.OutOrStdout())
+			w.Write(spec.Columns(operation))
+			w.WriteAll(spec.Rows(operation, rows))
+			if err := w.Error(); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	cmd.Flags().IntVarP(&rows, "rows", "n", 0, "Number of example rows to synthesize from schema examples, defaults and enums")
+	return cmd
+}
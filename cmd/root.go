@@ -3,11 +3,16 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/DustyRat/post-it/cmd/gen"
 	"github.com/DustyRat/post-it/cmd/method"
+	"github.com/DustyRat/post-it/cmd/scenario"
 
 	"github.com/DustyRat/post-it/internal/options"
+	"github.com/DustyRat/post-it/internal/threshold"
 	"github.com/spf13/cobra"
 )
 
@@ -51,18 +56,114 @@ All methods use the request_body column for requests.
 	cmd.SetUsageTemplate(template)
 
 	opts := options.Options{}
+	var percentiles string
+	var thresholds []string
+	cmd.PersistentFlags().StringVar(&opts.Client.Auth.Scheme, "auth", "basic", "Authentication scheme of --user: basic or digest")
+	cmd.PersistentFlags().StringVar(&opts.Client.AWSProfile, "aws-profile", "", "Shared credentials file profile for --aws-sigv4 (default AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY, then $AWS_PROFILE or default)")
+	cmd.PersistentFlags().StringVar(&opts.Client.SigV4, "aws-sigv4", "", "Sign every request with AWS Signature Version 4 for service/region, eg: execute-api/us-east-1")
+	cmd.PersistentFlags().StringVar(&opts.Client.TLS.CACert, "cacert", "", "PEM bundle of CAs to verify the server with instead of the system ones. Turns verification on unless --insecure is given.")
+	cmd.PersistentFlags().StringVar(&opts.Client.TLS.Cert, "cert", "", "Client certificate PEM file, or PKCS#12 file (.p12, .pfx) with --cert-password")
+	cmd.PersistentFlags().StringVar(&opts.Client.TLS.Password, "cert-password", "", "Password of a PKCS#12 --cert, or env:NAME / file:path to read it from")
+	cmd.PersistentFlags().StringVar(&opts.Client.OAuth2.ClientID, "client-id", "", "OAuth2 client ID, or env:NAME / file:path to read it from")
+	cmd.PersistentFlags().StringVar(&opts.Client.OAuth2.ClientSecret, "client-secret", "", "OAuth2 client secret, or env:NAME / file:path to read it from")
+	cmd.PersistentFlags().StringArrayVar(&opts.Compare.Headers, "compare-header", []string{}, "Response header to compare between both targets, requires --compare-url")
+	cmd.PersistentFlags().StringVar(&opts.Compare.URL, "compare-url", "", "Send every request to this base URL as well and compare the responses. Recorded to output file under the status_b and diff columns.")
 	cmd.PersistentFlags().IntVarP(&opts.Connections, "connections", "c", 10, "Concurrent connections")
-	cmd.PersistentFlags().BoolVarP(&opts.Flags.Errors, "errors", "e", false, "Record erorrs to output file")
+	cmd.PersistentFlags().StringVar(&opts.Client.Cookies.File, "cookie-file", "", "Netscape cookie file loaded before the run and replaced by the final cookies after it. Turns --cookies off into shared.")
+	cmd.PersistentFlags().StringVar(&opts.Client.Cookies.Mode, "cookies", "off", "Cookie jar: shared, per-row (a jar for each input row) or off")
+	cmd.PersistentFlags().BoolVar(&opts.Sinks.DogStatsD, "dogstatsd", false, "Tag the --statsd metrics with the method, status, URL template and error class in the DogStatsD format")
+	cmd.PersistentFlags().BoolVarP(&opts.Flags.Errors, "errors", "e", false, "Record errors to output file under the error and error_class columns.")
+	cmd.PersistentFlags().StringArrayVar(&thresholds, "fail-if", []string{}, "Exit with a non-zero code when a statistic of the run breaches this threshold, may be repeated. eg: 'error_rate>1%', 'p99>800ms', 'status_5xx>0'")
 	cmd.PersistentFlags().StringArrayVarP(&opts.Headers, "header", "H", []string{}, "HTTP headers to use (\"K: V\")")
+	cmd.PersistentFlags().StringVar(&opts.Client.HMAC.Algorithm, "hmac-algorithm", "sha256", "Hash algorithm of --hmac-secret signatures: sha1, sha256 or sha512")
+	cmd.PersistentFlags().StringVar(&opts.Client.HMAC.Encoding, "hmac-encoding", "hex", "Encoding of --hmac-secret signatures: hex or base64")
+	cmd.PersistentFlags().StringVar(&opts.Client.HMAC.Header, "hmac-header", "X-Signature", "Header of --hmac-secret signatures")
+	cmd.PersistentFlags().StringVar(&opts.Client.HMAC.Secret, "hmac-secret", "", "Sign every request with an HMAC of --hmac-template using this key, or env:NAME / file:path to read it from")
+	cmd.PersistentFlags().StringVar(&opts.Client.HMAC.Template, "hmac-template", "", "Canonical string of --hmac-secret signatures. May use input columns, {method}, {path}, {query}, {host}, {url}, {timestamp}, {timestamp_ms}, {body_hash} and {header:Name} (default {method}\\n{path}\\n{timestamp}\\n{body_hash})")
+	cmd.PersistentFlags().StringVar(&opts.Client.HMAC.TimestampHeader, "hmac-timestamp-header", "X-Timestamp", "Header of the {timestamp} signed by --hmac-secret, empty to leave it out")
 	cmd.PersistentFlags().BoolVarP(&opts.Histogram, "histogram", "g", false, "Print histogram statistics")
+	cmd.PersistentFlags().StringArrayVar(&opts.IgnorePaths, "ignore-path", []string{}, "JSONPath of a response body field to ignore when comparing or snapshotting, eg: $.timestamp, $..id, $.items[*].updated_at")
+	cmd.PersistentFlags().StringVar(&opts.Sinks.Influx, "influx", "", "Write the timings of every request as InfluxDB line protocol to this write endpoint or file. eg: http://localhost:8086/write?db=loadtest, results.lp")
 	cmd.PersistentFlags().StringVarP(&opts.Input, "input", "i", "input.csv", "Input File")
 	cmd.PersistentFlags().BoolVar(&opts.Client.InsecureSkipVerify, "insecure", true, "Insecure Skip Verify")
-	cmd.PersistentFlags().BoolVarP(&opts.Latency, "latencies", "l", false, "Print latency statistics")
+	cmd.PersistentFlags().StringVar(&opts.Suite.JUnit, "junit", "", "Write a JUnit XML test case of every row, or --testcase-group, to this file for CI test dashboards")
+	cmd.PersistentFlags().StringVar(&opts.Client.JWT.Algorithm, "jwt-alg", "RS256", "Algorithm of --jwt-key tokens: RS256, ES256 or HS256")
+	cmd.PersistentFlags().StringArrayVar(&opts.Client.JWT.Claims, "jwt-claim", []string{}, "Claim of --jwt-key tokens, eg: sub={user_id}, exp=now+5m, admin:=true (raw JSON)")
+	cmd.PersistentFlags().StringVar(&opts.Client.JWT.Header, "jwt-header", "Authorization", "Header of --jwt-key tokens, sent as a bearer token for Authorization")
+	cmd.PersistentFlags().StringVar(&opts.Client.JWT.Key, "jwt-key", "", "Mint a signed JWT for every request with this PEM private key file (RS256, ES256) or secret file (HS256), or env:NAME / file:path to read it from")
+	cmd.PersistentFlags().StringVar(&opts.Client.JWT.KeyID, "jwt-kid", "", "Key ID (kid) of --jwt-key tokens")
+	cmd.PersistentFlags().StringVar(&opts.Client.TLS.Key, "key", "", "Private key PEM file of --cert (default the --cert file)")
+	cmd.PersistentFlags().BoolVarP(&opts.Latency, "latencies", "l", false, "Print the latency distribution at --percentiles")
+	cmd.PersistentFlags().StringVar(&opts.Metrics.Addr, "metrics-addr", "", "Serve Prometheus metrics of the run at /metrics on this address while it goes on. The request rate is not exported, there is no configured one: use rate(http_outbound_requests_total). eg: :9100")
+	cmd.PersistentFlags().DurationVar(&opts.Metrics.Grace, "metrics-grace", 0, "Keep serving --metrics-addr for this long after the run. eg: 30s")
+	cmd.PersistentFlags().StringVar(&opts.OpenAPI, "openapi", "", "Validate requests and responses against an OpenAPI 3 spec (JSON or YAML). Violations are recorded to output file under the openapi_errors column.")
+	cmd.PersistentFlags().StringVar(&opts.Client.OAuth2.TokenURL, "oauth2-token-url", "", "OAuth2 token endpoint. Access tokens are fetched with the client credentials grant (password grant with --username), cached, renewed and sent as \"Authorization: Bearer\".")
 	cmd.PersistentFlags().StringVarP(&opts.Output, "output", "o", "output.csv", "Output File")
 	cmd.PersistentFlags().BoolVarP(&opts.Flags.Body, "record-body", "b", false, "Record body to output file under the response_body column.")
 	cmd.PersistentFlags().BoolVar(&opts.Flags.Headers, "record-headers", false, "Record headers to output file under the headers column.")
-	cmd.PersistentFlags().StringVarP(&opts.Flags.Status, "response-status", "s", "-2xx", "Record response status to output file under the headers status. eg: any, none, 2xx, -2xx (non 2xx statuses), 4xx, 5xx, 200, 301, 404, 503...")
+	cmd.PersistentFlags().BoolVar(&opts.Flags.Timings, "record-timings", false, "Record the DNS, connect, TLS, time to first byte and transfer times in ms and whether the connection was reused to output file under the dns_ms, connect_ms, tls_ms, ttfb_ms, transfer_ms and reused columns.")
+	cmd.PersistentFlags().StringVar(&opts.Client.OAuth2.Password, "password", "", "OAuth2 password grant password, or env:NAME / file:path to read it from")
+	cmd.PersistentFlags().StringVar(&percentiles, "percentiles", "50,90,99", "Percentiles of the latencies to print in the statistics. eg: 50,90,99,99.9")
+	cmd.PersistentFlags().StringArrayVar(&opts.Client.TLS.Pins, "pin", []string{}, "Base64 SHA-256 hash of the server's public key (SPKI), eg: sha256//AbC...=, may be repeated. Checked even with --insecure.")
+	cmd.PersistentFlags().StringVar(&opts.Refresh.File, "refresh", "", "Request file (JSON or YAML) sent to renew credentials when a response matches --refresh-status. Its extracted values re-expand the headers and the request is retried once.")
+	cmd.PersistentFlags().StringVar(&opts.Refresh.Status, "refresh-status", "401", "Response status that triggers --refresh. eg: 401, 401,403")
+	cmd.PersistentFlags().StringVar(&opts.Report.File, "report", "", "Write a self-contained HTML report of the run (configuration, statistics, latency and throughput charts, errors and slowest requests) to this file")
+	cmd.PersistentFlags().IntVar(&opts.Report.Slowest, "report-slowest", 10, "Number of slowest requests, with their input fields, listed in the --report")
+	cmd.PersistentFlags().StringVarP(&opts.Flags.Status, "response-status", "s", "-2xx", "Record response status to output file under the headers status. eg: any, none, 2xx, -2xx (non 2xx statuses), 4xx, 5xx, 200, 301, 404, 503, 2xx,404...")
+	cmd.PersistentFlags().BoolVar(&opts.Safe, "safe", false, "Verify server certificates unless --insecure is given explicitly")
+	cmd.PersistentFlags().StringVar(&opts.Schema, "schema", "", "Validate response bodies against a JSON Schema file. Violations are recorded to output file under the schema_errors column.")
+	cmd.PersistentFlags().StringArrayVar(&opts.Client.OAuth2.Scopes, "scope", []string{}, "OAuth2 scope to request, may be repeated")
+	cmd.PersistentFlags().StringVar(&opts.Client.TLS.ServerName, "server-name", "", "Server name sent for SNI and verified, instead of the host of the URL")
+	cmd.PersistentFlags().StringVar(&opts.Setup, "setup", "", "Request file (JSON or YAML) sent once before the run. Its extracted values can be used as {name} in headers, the URL and request bodies.")
+	cmd.PersistentFlags().IntVar(&opts.Sinks.Config.BatchSize, "sink-batch-size", 100, "Number of lines sent at once to --statsd and --influx")
+	cmd.PersistentFlags().DurationVar(&opts.Sinks.Config.FlushInterval, "sink-flush-interval", time.Second, "Send a partial batch to --statsd and --influx after this long")
+	cmd.PersistentFlags().Float64Var(&opts.Sinks.Config.SampleRate, "sink-sample-rate", 1, "Fraction of the requests sent to --statsd and --influx, from 0 to 1")
+	cmd.PersistentFlags().StringVar(&opts.Snapshot.Dir, "snapshot-dir", "", "Directory of golden response snapshots, one file per row. Results are recorded to output file under the snapshot column.")
+	cmd.PersistentFlags().StringVar(&opts.Snapshot.Key, "snapshot-key", "", "Input column used to name snapshot files (default first column)")
+	cmd.PersistentFlags().StringVar(&opts.Snapshot.Mode, "snapshot-mode", "verify", "record: store every response as a snapshot, verify: compare responses to the stored snapshots")
+	cmd.PersistentFlags().StringVar(&opts.Sinks.StatsD, "statsd", "", "Send the timing and status of every request to this StatsD server over UDP. eg: localhost:8125")
+	cmd.PersistentFlags().StringVar(&opts.Sinks.Prefix, "statsd-prefix", "post_it", "Prefix of the --statsd metric names")
+	cmd.PersistentFlags().StringVar(&opts.SummaryJSON, "summary-json", "", "Write every statistic of the run, with the counts of each status and error class, to this JSON file")
+	cmd.PersistentFlags().StringVar(&opts.Suite.TAP, "tap", "", "Write a TAP test case of every row, or --testcase-group, to this file")
+	cmd.PersistentFlags().StringVar(&opts.Teardown, "teardown", "", "Request file (JSON or YAML) sent once after the run, with the values extracted by --setup")
+	cmd.PersistentFlags().StringVar(&opts.Suite.Group, "testcase-group", "", "Input column whose rows make a single --junit and --tap test case")
+	cmd.PersistentFlags().StringVar(&opts.Suite.Name, "testcase-name", "{method} {url}", "Name of the --junit and --tap test cases. May use input columns, {method}, {url} and {row}. eg: {method} {id}")
+	cmd.PersistentFlags().StringVar(&opts.Suite.Status, "testcase-status", "2xx", "Response status a --junit and --tap test case must match to pass. eg: any, 2xx, -5xx (non 5xx statuses), 200...")
 	cmd.PersistentFlags().DurationVarP(&opts.Client.Timeout, "timeout", "t", 3000*time.Millisecond, "Connection timeout")
+	cmd.PersistentFlags().StringVar(&opts.Timeseries.File, "timeseries", "", "Write the requests, statuses, errors, latency percentiles and bytes of every --timeseries-interval of the run to this CSV file")
+	cmd.PersistentFlags().DurationVar(&opts.Timeseries.Interval, "timeseries-interval", time.Second, "Interval of the --timeseries rows")
+	cmd.PersistentFlags().StringVar(&opts.Client.TLS.MinVersion, "tls-min-version", "", "Lowest TLS version accepted: 1.0, 1.1, 1.2 or 1.3")
+	cmd.PersistentFlags().BoolVar(&opts.Snapshot.Update, "update", false, "Accept changed and missing snapshots in verify mode")
+	cmd.PersistentFlags().StringVar(&opts.Client.Auth.User, "user", "", "Credentials as user:pass for --auth, or env:NAME / file:path to read them from")
+	cmd.PersistentFlags().StringVar(&opts.Client.OAuth2.Username, "username", "", "OAuth2 password grant username, or env:NAME / file:path to read it from")
+
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		// Verification is only skipped when asked for explicitly in safe
+		// mode or with a CA bundle.
+		if (opts.Safe || opts.Client.TLS.CACert != "") && !cmd.Flags().Changed("insecure") {
+			opts.Client.InsecureSkipVerify = false
+		}
+		// The digest is added once the connection is picked, after the
+		// signers signed the headers.
+		if strings.EqualFold(opts.Client.Auth.Scheme, "digest") && (opts.Client.SigV4 != "" || opts.Client.HMAC.Secret != "" || opts.Client.JWT.Key != "") {
+			return fmt.Errorf("--auth digest can't be combined with --aws-sigv4, --hmac-secret or --jwt-key")
+		}
+		for _, p := range strings.Split(percentiles, ",") {
+			value, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if err != nil || value < 0 || value > 100 {
+				return fmt.Errorf("invalid percentile %q, expected a number from 0 to 100", p)
+			}
+			opts.Percentiles = append(opts.Percentiles, value)
+		}
+		for _, expr := range thresholds {
+			t, err := threshold.Parse(expr)
+			if err != nil {
+				return err
+			}
+			opts.Thresholds = append(opts.Thresholds, t)
+		}
+		return nil
+	}
 
 	cmd.AddCommand(method.NewCmdDelete(&opts))
 	cmd.AddCommand(method.NewCmdGet(&opts))
@@ -70,6 +171,8 @@ All methods use the request_body column for requests.
 	cmd.AddCommand(method.NewCmdPatch(&opts))
 	cmd.AddCommand(method.NewCmdPost(&opts))
 	cmd.AddCommand(method.NewCmdPut(&opts))
+	cmd.AddCommand(gen.NewCmdGen(&opts))
+	cmd.AddCommand(scenario.NewCmdScenario(&opts))
 	if err := cmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -0,0 +1,59 @@
+package scenario
+
+import (
+	"log"
+
+	"github.com/DustyRat/post-it/internal/controller"
+	"github.com/DustyRat/post-it/internal/file/csv"
+	internal "github.com/DustyRat/post-it/internal/http"
+	"github.com/DustyRat/post-it/internal/options"
+	"github.com/DustyRat/post-it/internal/scenario"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCmdScenario ...
+func NewCmdScenario(opts *options.Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "scenario <file>",
+		Args:    cobra.ExactArgs(1),
+		Short:   "Run the steps of a scenario file (JSON or YAML) for every row, chaining variables extracted from each response.",
+		Example: "post-it scenario ./order.json -i orders.csv",
+		Run: func(cmd *cobra.Command, args []string) {
+			opts.Scenario = args[0]
+			s, err := scenario.Load(opts.Scenario)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			opts.Client.Headers = internal.ParseHeaders(opts.Headers)
+			client, err := internal.New(opts.Client)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			var writer *csv.Writer
+			if opts.Output != "" {
+				writer, err = csv.NewWriter(opts.Output)
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+
+			ctrl := controller.Controller{
+				Options:  opts,
+				Client:   client,
+				Routines: opts.Connections,
+				Writer:   writer,
+				Scenario: s,
+			}
+
+			err = ctrl.Run(opts.Input, "", "")
+			if err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	return cmd
+}
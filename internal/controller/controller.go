@@ -2,17 +2,30 @@ package controller
 
 import (
 	"errors"
+	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/DustyRat/post-it/internal/file/csv"
 	"github.com/DustyRat/post-it/internal/http"
+	"github.com/DustyRat/post-it/internal/jsonpath"
+	"github.com/DustyRat/post-it/internal/openapi"
 	"github.com/DustyRat/post-it/internal/options"
+	"github.com/DustyRat/post-it/internal/report"
+	"github.com/DustyRat/post-it/internal/scenario"
+	"github.com/DustyRat/post-it/internal/schema"
+	"github.com/DustyRat/post-it/internal/sink"
+	"github.com/DustyRat/post-it/internal/snapshot"
 	"github.com/DustyRat/post-it/internal/stats"
+	"github.com/DustyRat/post-it/internal/suite"
 	"github.com/DustyRat/post-it/internal/worker"
 
 	"github.com/goinggo/work"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/vbauerster/mpb/v5"
 )
 
@@ -22,6 +35,13 @@ type Controller struct {
 	Client   *http.Client
 	Routines int
 	Writer   *csv.Writer
+
+	// Scenario, when set, runs its steps for every row instead of a single request.
+	Scenario *scenario.Scenario
+
+	// Stats records the results of the requests of the run, a new collector
+	// is used when it's nil.
+	Stats *stats.Collector
 }
 
 // Run ...
@@ -32,34 +52,359 @@ func (c *Controller) Run(file, method, rawURL string) error {
 	}
 	defer input.Close()
 
+	var validator *schema.Schema
+	if c.Options.Schema != "" {
+		validator, err = schema.Load(c.Options.Schema)
+		if err != nil {
+			return err
+		}
+	}
+
+	var spec *openapi.Spec
+	if c.Options.OpenAPI != "" {
+		spec, err = openapi.Load(c.Options.OpenAPI)
+		if err != nil {
+			return err
+		}
+	}
+
+	var comparison *worker.Comparison
+	if c.Options.Compare.URL != "" {
+		comparison, err = c.comparison()
+		if err != nil {
+			return err
+		}
+	}
+
+	var setup, teardown, refresh *scenario.Step
+	if c.Options.Setup != "" {
+		setup, err = scenario.LoadStep(c.Options.Setup)
+		if err != nil {
+			return err
+		}
+	}
+	if c.Options.Refresh.File != "" {
+		refresh, err = scenario.LoadStep(c.Options.Refresh.File)
+		if err != nil {
+			return err
+		}
+	}
+	if c.Options.Teardown != "" {
+		teardown, err = scenario.LoadStep(c.Options.Teardown)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Stats == nil {
+		c.Stats = stats.New()
+	}
+	if c.Options.Report.File != "" {
+		c.Stats.KeepSlowest(c.Options.Report.Slowest)
+	}
+	sinks, err := c.sinks()
+	if err != nil {
+		return err
+	}
+	recorders := http.Recorders{c.Stats}
+	for _, s := range sinks {
+		recorders = append(recorders, s)
+	}
+	c.Client.SetRecorder(recorders)
+
 	reader := csv.NewReader(input, method, rawURL, "request_body")
+	vars := make(map[string]string)
+	if setup != nil {
+		if result := setup.Do(c.Client.Untracked(), vars); result.Err != nil {
+			return fmt.Errorf("setup %s: %w", setup.Name, result.Err)
+		}
+		c.Client.SetVariables(vars)
+		reader.SetVariables(vars)
+	}
+	if refresh != nil {
+		untracked := c.Client.Untracked()
+		c.Client.SetRefresh(c.Options.Refresh.Status, func() (map[string]string, error) {
+			// Refreshes are serialized by the client, so vars isn't shared.
+			next := make(map[string]string, len(vars))
+			for k, v := range vars {
+				next[k] = v
+			}
+			if result := refresh.Do(untracked, next); result.Err != nil {
+				return nil, fmt.Errorf("%s: %w", refresh.Name, result.Err)
+			}
+			vars = next
+			return vars, nil
+		})
+	}
+
+	var snapshots *worker.Snapshots
+	if c.Options.Snapshot.Dir != "" {
+		snapshots, err = c.snapshots(reader.Headers())
+		if err != nil {
+			return err
+		}
+	}
 	wp, err := work.New(c.Routines, time.Hour*24, func(message string) {})
 	if err != nil {
 		return errors.New("error creating worker pools")
 	}
 
 	progress := mpb.New()
-	pool := worker.NewPool(c.Options, wp, c.Client, progress, reader.Count(), reader, c.Writer)
+	pool := worker.NewPool(c.Options, wp, c.Client, c.Stats, progress, reader.Count(), reader, c.Writer)
+	if validator != nil {
+		pool.SetSchema(validator)
+		c.Options.Flags.Schema = true
+	}
+	if c.Scenario != nil {
+		pool.SetScenario(c.Scenario)
+		c.Options.Flags.Scenario = true
+	}
+	if comparison != nil {
+		pool.SetComparison(comparison)
+		c.Options.Flags.Compare = true
+	}
+	if snapshots != nil {
+		pool.SetSnapshots(snapshots)
+		c.Options.Flags.Snapshot = true
+	}
+	if spec != nil {
+		pool.SetSpec(spec)
+		c.Options.Flags.OpenAPI = true
+	}
+	var cases *suite.Suite
+	if c.Options.Suite.JUnit != "" || c.Options.Suite.TAP != "" {
+		cases = suite.New(c.Options.Suite.Name, c.Options.Suite.Group)
+		pool.SetSuite(cases)
+	}
 
 	headers := reader.Headers()
 	if c.Writer != nil {
 		headers = append(headers, "status")
+		if c.Options.Flags.Compare {
+			headers = append(headers, "status_b")
+		}
 		if c.Options.Flags.Headers {
 			headers = append(headers, "headers")
 		}
 		if c.Options.Flags.Body {
 			headers = append(headers, "response_body")
 		}
+		if c.Options.Flags.Timings {
+			headers = append(headers, "dns_ms", "connect_ms", "tls_ms", "ttfb_ms", "transfer_ms", "reused")
+		}
+		if c.Options.Flags.Schema {
+			headers = append(headers, "schema_errors")
+		}
+		if c.Options.Flags.OpenAPI {
+			headers = append(headers, "openapi_errors")
+		}
+		if c.Options.Flags.Compare {
+			headers = append(headers, "diff")
+		}
+		if c.Options.Flags.Snapshot {
+			headers = append(headers, "snapshot")
+		}
+		if c.Options.Flags.Scenario {
+			headers = append(headers, "failed_step")
+		}
 		if c.Options.Flags.Errors {
-			headers = append(headers, "error")
+			headers = append(headers, "error", "error_class")
 		}
 		c.Writer.Write(headers)
 	}
 
+	var server *stats.Server
+	if c.Options.Metrics.Addr != "" {
+		if server, err = c.serve(pool); err != nil {
+			return err
+		}
+	}
+
 	for i := 0; i < reader.Count(); i++ {
 		pool.NewWorker()
 	}
+	started := time.Now()
 	elapsed := pool.Run()
-	stats.Print(*c.Options, elapsed)
+	for _, s := range sinks {
+		if err := s.Close(); err != nil {
+			log.Printf("sink: %s", err)
+		}
+	}
+	if teardown != nil {
+		if result := teardown.Do(c.Client.Untracked(), vars); result.Err != nil {
+			log.Printf("teardown %s: %s", teardown.Name, result.Err)
+		}
+	}
+	if err := c.Client.SaveCookies(); err != nil {
+		log.Printf("cookies: %s", err)
+	}
+	stats.Print(*c.Options, c.Stats, elapsed)
+	if c.Options.Report.File != "" {
+		run := report.Run{
+			Method:      method,
+			URL:         rawURL,
+			Scenario:    c.Options.Scenario,
+			Input:       file,
+			Headers:     reader.Headers(),
+			Rows:        reader.Count(),
+			Connections: c.Routines,
+			Timeout:     c.Options.Client.Timeout,
+			Started:     started,
+			Elapsed:     elapsed,
+		}
+		if err := report.Write(c.Options.Report.File, run, c.Stats, stats.Percentiles(*c.Options)); err != nil {
+			log.Printf("report: %s", err)
+		}
+	}
+	if cases != nil {
+		c.writeSuite(cases, method, rawURL, started, elapsed)
+	}
+	summary := stats.Summarize(c.Stats, stats.Percentiles(*c.Options), elapsed)
+	summary.Thresholds = summary.Check(c.Options.Thresholds, c.Stats)
+	stats.PrintChecks(os.Stdout, summary.Thresholds)
+	if c.Options.SummaryJSON != "" {
+		if err := stats.WriteSummary(c.Options.SummaryJSON, summary); err != nil {
+			log.Printf("summary: %s", err)
+		}
+	}
+	if server != nil {
+		if c.Options.Metrics.Grace > 0 {
+			log.Printf("serving metrics at %s for %s", c.Options.Metrics.Addr, c.Options.Metrics.Grace)
+		}
+		if err := server.Close(c.Options.Metrics.Grace); err != nil {
+			log.Printf("metrics: %s", err)
+		}
+	}
+	if spec != nil {
+		spec.Report(os.Stdout)
+	}
+	if breached := stats.Breached(summary.Thresholds); len(breached) > 0 {
+		exprs := make([]string, len(breached))
+		for i, check := range breached {
+			exprs[i] = check.Threshold
+		}
+		return fmt.Errorf("%d of %d thresholds breached: %s", len(breached), len(summary.Thresholds), strings.Join(exprs, ", "))
+	}
 	return nil
 }
+
+// writeSuite writes the test cases of the run as JUnit XML and TAP.
+func (c *Controller) writeSuite(cases *suite.Suite, method, rawURL string, started time.Time, elapsed time.Duration) {
+	name := strings.TrimSpace(method + " " + rawURL)
+	if c.Options.Scenario != "" {
+		name = filepath.Base(c.Options.Scenario)
+	}
+	if file := c.Options.Suite.JUnit; file != "" {
+		if err := suite.WriteJUnit(file, name, cases.Cases(), started, elapsed); err != nil {
+			log.Printf("junit: %s", err)
+		}
+	}
+	if file := c.Options.Suite.TAP; file != "" {
+		if err := suite.WriteTAP(file, cases.Cases()); err != nil {
+			log.Printf("tap: %s", err)
+		}
+	}
+}
+
+// sinks connects to the configured metrics backends and opens the time
+// series file.
+func (c *Controller) sinks() ([]sink.Sink, error) {
+	conf := c.Options.Sinks
+	sinks := make([]sink.Sink, 0)
+	if conf.StatsD != "" {
+		s, err := sink.NewStatsD(conf.StatsD, conf.Prefix, conf.DogStatsD, conf.Config)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	if conf.Influx != "" {
+		s, err := sink.NewInflux(conf.Influx, conf.Config)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	if c.Options.Timeseries.File != "" {
+		s, err := stats.NewTimeseries(c.Options.Timeseries.File, c.Options.Timeseries.Interval)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+// serve exposes the metrics of the run while it goes on.
+func (c *Controller) serve(pool *worker.Pool) (*stats.Server, error) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		c.Stats.Prometheus(),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "post_it_rows_remaining",
+			Help: "Number of input rows not done yet.",
+		}, func() float64 { return float64(pool.Remaining()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "post_it_concurrency",
+			Help: "Number of rows sent concurrently.",
+		}, func() float64 { return float64(c.Routines) }),
+	)
+	return stats.Serve(c.Options.Metrics.Addr, registry)
+}
+
+func (c *Controller) comparison() (*worker.Comparison, error) {
+	uri, err := url.Parse(c.Options.Compare.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	ignore, err := c.ignorePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	// The configured headers are shared with the client of the run, to send
+	// them as expanded by --setup and refreshed.
+	client, err := http.New(c.Options.Client)
+	if err != nil {
+		return nil, err
+	}
+	client.ShareHeaders(c.Client)
+	return &worker.Comparison{Client: client, URL: uri, Headers: c.Options.Compare.Headers, Ignore: ignore}, nil
+}
+
+func (c *Controller) ignorePaths() ([]*jsonpath.Path, error) {
+	paths := make([]*jsonpath.Path, 0, len(c.Options.IgnorePaths))
+	for _, expression := range c.Options.IgnorePaths {
+		path, err := jsonpath.Parse(expression)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func (c *Controller) snapshots(columns []string) (*worker.Snapshots, error) {
+	key := c.Options.Snapshot.Key
+	if key == "" && len(columns) > 0 {
+		key = columns[0]
+	}
+	found := false
+	for _, column := range columns {
+		found = found || column == key
+	}
+	if !found {
+		return nil, fmt.Errorf("snapshot key column %q not found in input file", key)
+	}
+
+	ignore, err := c.ignorePaths()
+	if err != nil {
+		return nil, err
+	}
+	store, err := snapshot.New(c.Options.Snapshot.Dir, snapshot.Mode(c.Options.Snapshot.Mode), c.Options.Snapshot.Update, ignore)
+	if err != nil {
+		return nil, err
+	}
+	return &worker.Snapshots{Store: store, Key: key}, nil
+}
@@ -0,0 +1,174 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/DustyRat/post-it/internal/jsonpath"
+)
+
+// Kind ...
+type Kind string
+
+// Kinds of change between two documents, from the point of view of the second.
+const (
+	Added   Kind = "added"
+	Removed Kind = "removed"
+	Changed Kind = "changed"
+)
+
+// Change ...
+type Change struct {
+	Path string
+	Kind Kind
+	A    interface{}
+	B    interface{}
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("%s added: %s", c.Path, compact(c.B))
+	case Removed:
+		return fmt.Sprintf("%s removed: %s", c.Path, compact(c.A))
+	default:
+		return fmt.Sprintf("%s: %s != %s", c.Path, compact(c.A), compact(c.B))
+	}
+}
+
+// Bodies compares two response bodies. Bodies that are both valid JSON are
+// compared structurally, so key order and whitespace don't matter, and
+// locations selected by ignore are skipped. Anything else is compared byte
+// for byte.
+func Bodies(a, b []byte, ignore []*jsonpath.Path) []Change {
+	var x, y interface{}
+	if json.Unmarshal(a, &x) == nil && json.Unmarshal(b, &y) == nil {
+		return JSON(x, y, ignore)
+	}
+	if bytes.Equal(bytes.TrimSpace(a), bytes.TrimSpace(b)) {
+		return nil
+	}
+	return []Change{{Path: "$", Kind: Changed, A: abbreviate(a), B: abbreviate(b)}}
+}
+
+// JSON compares two decoded JSON documents.
+func JSON(a, b interface{}, ignore []*jsonpath.Path) []Change {
+	d := &differ{ignore: ignore, changes: make([]Change, 0)}
+	d.compare(a, b, nil, nil)
+	return d.changes
+}
+
+// Strip returns a copy of doc without the locations selected by ignore.
+func Strip(doc interface{}, ignore []*jsonpath.Path) interface{} {
+	return strip(doc, ignore, nil)
+}
+
+func strip(node interface{}, ignore []*jsonpath.Path, keys []string) interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(n))
+		for key, value := range n {
+			location := append(keys[:len(keys):len(keys)], key)
+			if !ignored(ignore, location) {
+				out[key] = strip(value, ignore, location)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, 0, len(n))
+		for i, value := range n {
+			location := append(keys[:len(keys):len(keys)], strconv.Itoa(i))
+			if !ignored(ignore, location) {
+				out = append(out, strip(value, ignore, location))
+			}
+		}
+		return out
+	}
+	return node
+}
+
+type differ struct {
+	ignore  []*jsonpath.Path
+	changes []Change
+}
+
+func (d *differ) compare(a, b interface{}, keys []string, indexes []bool) {
+	if ignored(d.ignore, keys) {
+		return
+	}
+
+	switch x := a.(type) {
+	case map[string]interface{}:
+		if y, ok := b.(map[string]interface{}); ok {
+			for _, key := range jsonpath.Keys(x) {
+				k, i := append(keys[:len(keys):len(keys)], key), append(indexes[:len(indexes):len(indexes)], false)
+				if value, ok := y[key]; ok {
+					d.compare(x[key], value, k, i)
+				} else if !ignored(d.ignore, k) {
+					d.add(Change{Path: jsonpath.Format(k, i), Kind: Removed, A: x[key]})
+				}
+			}
+			for _, key := range jsonpath.Keys(y) {
+				k, i := append(keys[:len(keys):len(keys)], key), append(indexes[:len(indexes):len(indexes)], false)
+				if _, ok := x[key]; !ok && !ignored(d.ignore, k) {
+					d.add(Change{Path: jsonpath.Format(k, i), Kind: Added, B: y[key]})
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if y, ok := b.([]interface{}); ok {
+			for n := 0; n < len(x) || n < len(y); n++ {
+				k, i := append(keys[:len(keys):len(keys)], strconv.Itoa(n)), append(indexes[:len(indexes):len(indexes)], true)
+				switch {
+				case n >= len(y):
+					if !ignored(d.ignore, k) {
+						d.add(Change{Path: jsonpath.Format(k, i), Kind: Removed, A: x[n]})
+					}
+				case n >= len(x):
+					if !ignored(d.ignore, k) {
+						d.add(Change{Path: jsonpath.Format(k, i), Kind: Added, B: y[n]})
+					}
+				default:
+					d.compare(x[n], y[n], k, i)
+				}
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		d.add(Change{Path: jsonpath.Format(keys, indexes), Kind: Changed, A: a, B: b})
+	}
+}
+
+func (d *differ) add(c Change) {
+	d.changes = append(d.changes, c)
+}
+
+func ignored(ignore []*jsonpath.Path, keys []string) bool {
+	for _, path := range ignore {
+		if path.Match(keys) {
+			return true
+		}
+	}
+	return false
+}
+
+func compact(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return abbreviate(b)
+}
+
+func abbreviate(b []byte) string {
+	if len(b) > 64 {
+		return string(b[:61]) + "..."
+	}
+	return string(b)
+}
@@ -17,6 +17,8 @@ import (
 
 // Record ...
 type Record struct {
+	// Row is the number of the record in the file, from 1.
+	Row     int
 	Headers []string
 	Body    []byte
 	Fields  map[string]string
@@ -31,11 +33,13 @@ type Reader struct {
 	reader *csv.Reader
 
 	count   int
+	read    int
 	headers []string
 	body    string
 
 	method string
 	rawurl string
+	vars   map[string]string
 }
 
 // NewReader ...
@@ -100,10 +104,16 @@ func (r *Reader) Read() *Record {
 		log.Fatal(err)
 	}
 
-	record := Record{Headers: r.headers, Fields: make(map[string]string)}
+	r.read++
+	record := Record{Row: r.read, Headers: r.headers, Fields: make(map[string]string)}
 	for i := range r.headers {
 		record.Fields[r.headers[i]] = line[i]
 	}
+	for k, v := range r.vars {
+		if _, ok := record.Fields[k]; !ok {
+			record.Fields[k] = v
+		}
+	}
 	if b, ok := record.Fields[r.body]; ok {
 		record.Body = []byte(b)
 	}
@@ -112,6 +122,12 @@ func (r *Reader) Read() *Record {
 	return &record
 }
 
+// SetVariables makes vars available to every record as if they were
+// columns. Columns of the input file take precedence.
+func (r *Reader) SetVariables(vars map[string]string) {
+	r.vars = vars
+}
+
 // Headers ...
 func (r Reader) Headers() []string {
 	return r.headers
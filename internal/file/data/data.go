@@ -0,0 +1,73 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ReadFile reads a JSON or YAML file into v, see Decode.
+func ReadFile(name string, v interface{}) error {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	return Decode(name, b, v)
+}
+
+// Decode decodes b into v as if it were JSON. Files with a .yaml or .yml
+// extension are parsed as YAML first, so v sees the same types either way
+// (maps with string keys, float64 numbers).
+func Decode(name string, b []byte, v interface{}) error {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		var doc interface{}
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		doc, err := normalize(doc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		b, err = json.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// normalize converts the map[interface{}]interface{} values produced by the
+// YAML decoder into something encoding/json accepts.
+func normalize(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for key, value := range t {
+			value, err := normalize(value)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(key)] = value
+		}
+		return m, nil
+	case []interface{}:
+		for i := range t {
+			value, err := normalize(t[i])
+			if err != nil {
+				return nil, err
+			}
+			t[i] = value
+		}
+		return t, nil
+	}
+	return v, nil
+}
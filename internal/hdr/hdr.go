@@ -0,0 +1,239 @@
+// Package hdr records values in a high dynamic range histogram: buckets are
+// linear within each power of two, so every value is kept to within 0.1% of
+// its size from nanoseconds to hours in a few hundred kilobytes.
+package hdr
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+)
+
+// precision is the number of bits of each value kept, giving 1024 linear
+// sub-buckets per power of two.
+const precision = 10
+
+// Histogram ...
+type Histogram struct {
+	mutex  sync.Mutex
+	counts []uint64
+	count  uint64
+	min    int64
+	max    int64
+	sum    float64
+	mean   float64
+	// squares is the sum of the squared distances from the mean, kept with
+	// Welford's method so the standard deviation is exact.
+	squares float64
+}
+
+// New ...
+func New() *Histogram {
+	return &Histogram{}
+}
+
+// Record adds a duration to the histogram. Negative durations count as 0.
+func (h *Histogram) Record(d time.Duration) {
+	h.RecordValue(int64(d))
+}
+
+// RecordValue adds a value to the histogram. Negative values count as 0.
+func (h *Histogram) RecordValue(v int64) {
+	if v < 0 {
+		v = 0
+	}
+	i := index(v)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if i >= len(h.counts) {
+		counts := make([]uint64, i+1)
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+	h.counts[i]++
+	if h.count == 0 || v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+	h.count++
+	h.sum += float64(v)
+	delta := float64(v) - h.mean
+	h.mean += delta / float64(h.count)
+	h.squares += delta * (float64(v) - h.mean)
+}
+
+// Merge adds the values of other to the histogram.
+func (h *Histogram) Merge(other *Histogram) {
+	other.mutex.Lock()
+	counts := append([]uint64(nil), other.counts...)
+	count, min, max, sum, mean, squares := other.count, other.min, other.max, other.sum, other.mean, other.squares
+	other.mutex.Unlock()
+	if count == 0 {
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if len(counts) > len(h.counts) {
+		grown := make([]uint64, len(counts))
+		copy(grown, h.counts)
+		h.counts = grown
+	}
+	for i, c := range counts {
+		h.counts[i] += c
+	}
+	if h.count == 0 || min < h.min {
+		h.min = min
+	}
+	if max > h.max {
+		h.max = max
+	}
+	total := h.count + count
+	delta := mean - h.mean
+	h.squares += squares + delta*delta*float64(h.count)*float64(count)/float64(total)
+	h.mean += delta * float64(count) / float64(total)
+	h.count = total
+	h.sum += sum
+}
+
+// Count ...
+func (h *Histogram) Count() uint64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.count
+}
+
+// Sum ...
+func (h *Histogram) Sum() time.Duration {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return time.Duration(h.sum)
+}
+
+// Min ...
+func (h *Histogram) Min() time.Duration {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return time.Duration(h.min)
+}
+
+// Max ...
+func (h *Histogram) Max() time.Duration {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return time.Duration(h.max)
+}
+
+// Mean ...
+func (h *Histogram) Mean() time.Duration {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return time.Duration(h.mean)
+}
+
+// StdDev is the population standard deviation of the recorded values.
+func (h *Histogram) StdDev() time.Duration {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.count == 0 {
+		return 0
+	}
+	return time.Duration(math.Sqrt(h.squares / float64(h.count)))
+}
+
+// Percentile returns the value that p percent (0 - 100) of the recorded
+// values are at or below, e.g. Percentile(99.9).
+func (h *Histogram) Percentile(p float64) time.Duration {
+	return h.Percentiles(p)[0]
+}
+
+// Percentiles returns the values of each of ps in a single pass.
+func (h *Histogram) Percentiles(ps ...float64) []time.Duration {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	out := make([]time.Duration, len(ps))
+	if h.count == 0 {
+		return out
+	}
+	order := make([]int, len(ps))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return ps[order[i]] < ps[order[j]] })
+
+	var seen uint64
+	bucket := 0
+	for _, i := range order {
+		p := math.Min(math.Max(ps[i], 0), 100)
+		rank := uint64(math.Ceil(p / 100 * float64(h.count)))
+		if rank == 0 {
+			rank = 1
+		}
+		for ; bucket < len(h.counts); bucket++ {
+			if seen+h.counts[bucket] >= rank {
+				break
+			}
+			seen += h.counts[bucket]
+		}
+		if rank == h.count {
+			out[i] = time.Duration(h.max)
+			continue
+		}
+		out[i] = time.Duration(h.value(bucket))
+	}
+	return out
+}
+
+// CountAtOrBelow returns the number of values at or below v.
+func (h *Histogram) CountAtOrBelow(v time.Duration) uint64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	var count uint64
+	for i, c := range h.counts {
+		if low, _ := bounds(i); low > int64(v) {
+			break
+		}
+		count += c
+	}
+	return count
+}
+
+// value returns the representative value of bucket i, the middle of its
+// range clamped to the recorded min and max.
+func (h *Histogram) value(i int) int64 {
+	low, high := bounds(i)
+	v := low + (high-low)/2
+	if v < h.min {
+		v = h.min
+	}
+	if v > h.max {
+		v = h.max
+	}
+	return v
+}
+
+// index returns the bucket of v. Values below 2^precision have a bucket
+// each, above that every power of two has 2^precision buckets.
+func index(v int64) int {
+	if v < 1<<precision {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - 1 - precision
+	return (shift+1)<<precision + int(v>>uint(shift)-1<<precision)
+}
+
+// bounds returns the lowest and highest values of bucket i.
+func bounds(i int) (int64, int64) {
+	if i < 1<<precision {
+		return int64(i), int64(i)
+	}
+	shift := uint(i>>precision - 1)
+	low := (int64(i&(1<<precision-1)) + 1<<precision) << shift
+	return low, low + 1<<shift - 1
+}
@@ -0,0 +1,89 @@
+package hdr
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Rate counts events per second, from the second of the first event to the
+// second of the last.
+type Rate struct {
+	mutex   sync.Mutex
+	start   int64
+	seconds []uint64
+}
+
+// NewRate ...
+func NewRate() *Rate {
+	return &Rate{}
+}
+
+// Add counts an event at.
+func (r *Rate) Add(at time.Time) {
+	second := at.Unix()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.seconds) == 0 {
+		r.start = second
+	}
+	if second < r.start {
+		// Events of concurrent requests may be added out of order.
+		seconds := make([]uint64, int(r.start-second)+len(r.seconds))
+		copy(seconds[r.start-second:], r.seconds)
+		r.seconds, r.start = seconds, second
+	}
+	i := int(second - r.start)
+	for i >= len(r.seconds) {
+		r.seconds = append(r.seconds, 0)
+	}
+	r.seconds[i]++
+}
+
+// Seconds returns the number of events in each second.
+func (r *Rate) Seconds() []uint64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]uint64(nil), r.seconds...)
+}
+
+// Stats returns the min, mean, standard deviation and max of the events per
+// second.
+func (r *Rate) Stats() (min, mean, stddev, max float64) {
+	seconds := r.Seconds()
+	if len(seconds) == 0 {
+		return 0, 0, 0, 0
+	}
+	min = math.MaxFloat64
+	for _, n := range seconds {
+		mean += float64(n)
+		min = math.Min(min, float64(n))
+		max = math.Max(max, float64(n))
+	}
+	mean /= float64(len(seconds))
+	for _, n := range seconds {
+		stddev += math.Pow(float64(n)-mean, 2)
+	}
+	return min, mean, math.Sqrt(stddev / float64(len(seconds))), max
+}
+
+// Percentiles returns the events per second that p percent (0 - 100) of the
+// seconds are at or below, for each of ps.
+func (r *Rate) Percentiles(ps ...float64) []float64 {
+	seconds := r.Seconds()
+	out := make([]float64, len(ps))
+	if len(seconds) == 0 {
+		return out
+	}
+	sort.Slice(seconds, func(i, j int) bool { return seconds[i] < seconds[j] })
+	for i, p := range ps {
+		rank := int(math.Ceil(math.Min(math.Max(p, 0), 100) / 100 * float64(len(seconds))))
+		if rank == 0 {
+			rank = 1
+		}
+		out[i] = float64(seconds[rank-1])
+	}
+	return out
+}
@@ -0,0 +1,281 @@
+package http
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/DustyRat/post-it/internal/secret"
+)
+
+// Auth configures HTTP Basic or Digest authentication.
+type Auth struct {
+	// Scheme is basic or digest.
+	Scheme string
+
+	// User is user:pass, or env:NAME / file:path to read it from.
+	User string
+}
+
+// digest answers Digest challenges (RFC 7616, qop=auth). Challenges are
+// cached per connection, keyed by its addresses, so the nonce counts of a
+// nonce are sent in order. A connection that hasn't challenged yet, e.g. a
+// new one after the server closed the challenged one, takes the latest
+// challenge of its host. A request is authorized once its connection is
+// picked, before it is written.
+type digest struct {
+	mutex      sync.Mutex
+	user       string
+	password   string
+	challenges map[string]*challenge
+	hosts      map[string]*challenge
+}
+
+// maxChallenges caps the connections whose challenges are cached. Closed
+// connections aren't noticed, so beyond it an arbitrary one is forgotten;
+// it takes the challenge of its host again if it's still open.
+const maxChallenges = 1024
+
+type challenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+	count     uint32
+}
+
+// newAuth returns the credentials of conf, and a digest state when it uses
+// the digest scheme.
+func newAuth(conf Auth) (string, string, *digest, error) {
+	value, err := secret.Read(conf.User)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("auth: %w", err)
+	}
+	i := strings.Index(value, ":")
+	if i < 0 {
+		return "", "", nil, fmt.Errorf("auth: user is not user:pass")
+	}
+	user, password := value[:i], value[i+1:]
+
+	switch strings.ToLower(conf.Scheme) {
+	case "", "basic":
+		return user, password, nil, nil
+	case "digest":
+		return user, password, &digest{user: user, password: password, challenges: make(map[string]*challenge), hosts: make(map[string]*challenge)}, nil
+	}
+	return "", "", nil, fmt.Errorf("auth: unknown scheme %q, expected basic or digest", conf.Scheme)
+}
+
+// trace returns request authorizing itself for the challenge of the
+// connection it gets.
+func (d *digest) trace(request *http.Request) *http.Request {
+	header := request.Header
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			d.authorize(request.Method, request.URL, header, connection(info.Conn))
+		},
+	}
+	return request.WithContext(httptrace.WithClientTrace(request.Context(), trace))
+}
+
+// connection identifies a connection by its addresses.
+func connection(conn net.Conn) string {
+	return conn.LocalAddr().String() + "->" + conn.RemoteAddr().String()
+}
+
+// authorize adds the Authorization header for the challenge of conn, or
+// else of the host of u, to header. Until a host has challenged, requests
+// are sent without it.
+func (d *digest) authorize(method string, u *url.URL, header http.Header, conn string) {
+	d.mutex.Lock()
+	c, ok := d.challenges[conn]
+	if !ok {
+		if c, ok = d.hosts[u.Host]; !ok {
+			d.mutex.Unlock()
+			return
+		}
+		d.remember(conn, c)
+	}
+	c.count++
+	ch := *c
+	d.mutex.Unlock()
+
+	h := newHash(ch.algorithm)
+	cnonce := make([]byte, 8)
+	rand.Read(cnonce)
+	nc := fmt.Sprintf("%08x", ch.count)
+	cn := hex.EncodeToString(cnonce)
+	uri := u.RequestURI()
+
+	ha1 := digestHash(h, d.user+":"+ch.realm+":"+d.password)
+	if strings.HasSuffix(strings.ToLower(ch.algorithm), "-sess") {
+		ha1 = digestHash(h, ha1+":"+ch.nonce+":"+cn)
+	}
+	ha2 := digestHash(h, method+":"+uri)
+
+	var response string
+	parts := []string{
+		fmt.Sprintf("username=%q", d.user),
+		fmt.Sprintf("realm=%q", ch.realm),
+		fmt.Sprintf("nonce=%q", ch.nonce),
+		fmt.Sprintf("uri=%q", uri),
+	}
+	if ch.qop != "" {
+		response = digestHash(h, strings.Join([]string{ha1, ch.nonce, nc, cn, ch.qop, ha2}, ":"))
+		parts = append(parts, "qop="+ch.qop, "nc="+nc, fmt.Sprintf("cnonce=%q", cn))
+	} else {
+		response = digestHash(h, ha1+":"+ch.nonce+":"+ha2)
+	}
+	parts = append(parts, fmt.Sprintf("response=%q", response))
+	if ch.algorithm != "" {
+		parts = append(parts, "algorithm="+ch.algorithm)
+	}
+	if ch.opaque != "" {
+		parts = append(parts, fmt.Sprintf("opaque=%q", ch.opaque))
+	}
+	header.Set("Authorization", "Digest "+strings.Join(parts, ", "))
+}
+
+// challenged caches the Digest challenge of a 401 response for its
+// connection and host, e.g. the first one of a connection or one reporting
+// a stale nonce, and reports whether the request should be sent again.
+func (d *digest) challenged(response *Response) bool {
+	if response.connection == "" {
+		return false
+	}
+	var best map[string]string
+	for _, value := range response.Header.Values("WWW-Authenticate") {
+		if len(value) < 7 || !strings.EqualFold(value[:7], "Digest ") {
+			continue
+		}
+		params := parseChallenge(value[7:])
+		if !supported(params["algorithm"]) {
+			continue
+		}
+		// Servers may offer SHA-256 and MD5, the first is preferred.
+		if best == nil || strings.HasPrefix(strings.ToUpper(params["algorithm"]), "SHA-256") {
+			best = params
+		}
+	}
+	if best == nil {
+		return false
+	}
+
+	qop := ""
+	for _, q := range strings.Split(best["qop"], ",") {
+		if strings.TrimSpace(q) == "auth" {
+			qop = "auth"
+		}
+	}
+	if best["qop"] != "" && qop == "" {
+		// Only auth-int is offered.
+		return false
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if c, ok := d.challenges[response.connection]; ok && c.nonce == best["nonce"] && !strings.EqualFold(best["stale"], "true") {
+		// The current nonce was rejected, the credentials are wrong.
+		return c.nonce != authorizationNonce(response.Request)
+	}
+	c := &challenge{
+		realm:     best["realm"],
+		nonce:     best["nonce"],
+		opaque:    best["opaque"],
+		algorithm: best["algorithm"],
+		qop:       qop,
+	}
+	d.remember(response.connection, c)
+	d.hosts[response.Request.URL.Host] = c
+	return true
+}
+
+// remember caches the challenge of conn, the mutex must be held.
+func (d *digest) remember(conn string, c *challenge) {
+	if _, ok := d.challenges[conn]; !ok && len(d.challenges) >= maxChallenges {
+		for forgotten := range d.challenges {
+			delete(d.challenges, forgotten)
+			break
+		}
+	}
+	d.challenges[conn] = c
+}
+
+// authorizationNonce is the nonce a request was authorized with.
+func authorizationNonce(request *http.Request) string {
+	authorization := request.Header.Get("Authorization")
+	if len(authorization) < 7 || !strings.EqualFold(authorization[:7], "Digest ") {
+		return ""
+	}
+	return parseChallenge(authorization[7:])["nonce"]
+}
+
+func supported(algorithm string) bool {
+	switch strings.ToUpper(algorithm) {
+	case "", "MD5", "MD5-SESS", "SHA-256", "SHA-256-SESS":
+		return true
+	}
+	return false
+}
+
+func newHash(algorithm string) func() hash.Hash {
+	if strings.HasPrefix(strings.ToUpper(algorithm), "SHA-256") {
+		return sha256.New
+	}
+	return md5.New
+}
+
+func digestHash(h func() hash.Hash, s string) string {
+	sum := h()
+	sum.Write([]byte(s))
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// parseChallenge parses the comma separated key=value and key="value"
+// parameters of a challenge.
+func parseChallenge(s string) map[string]string {
+	params := make(map[string]string)
+	for len(s) > 0 {
+		s = strings.TrimLeft(s, " \t,")
+		i := strings.Index(s, "=")
+		if i < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:i]))
+		s = strings.TrimLeft(s[i+1:], " \t")
+
+		var value strings.Builder
+		if strings.HasPrefix(s, `"`) {
+			s = s[1:]
+			for len(s) > 0 && s[0] != '"' {
+				if s[0] == '\\' && len(s) > 1 {
+					s = s[1:]
+				}
+				value.WriteByte(s[0])
+				s = s[1:]
+			}
+			if len(s) > 0 {
+				s = s[1:]
+			}
+		} else {
+			i := strings.Index(s, ",")
+			if i < 0 {
+				i = len(s)
+			}
+			value.WriteString(strings.TrimSpace(s[:i]))
+			s = s[i:]
+		}
+		params[key] = value.String()
+	}
+	return params
+}
@@ -1,16 +1,16 @@
 package http
 
 import (
-	"crypto/tls"
+	"bytes"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
-
-	"github.com/prometheus/client_golang/prometheus"
 )
 
 // Response ...
@@ -27,63 +27,36 @@ type Response struct {
 	Uncompressed     bool
 	Trailer          http.Header
 	Duration         time.Duration
+	Timings          Timings
 	Request          *http.Request
+
+	// connection the response was read from, see digest.
+	connection string
 }
 
 // Client ...
 type Client struct {
-	client  *http.Client
-	url     *url.URL
-	headers http.Header
+	client   *http.Client
+	url      *url.URL
+	defaults *defaults
+	recorder Recorder
+	refresh  *refresh
+	token    *token
+	basic    *url.Userinfo
+	digest   *digest
+	signers  []Signer
+	sigv4    *sigv4
+	sessions *sessions
+	jar      *Jar
 }
 
-var (
-	registry                       = prometheus.NewRegistry()
-	Registry prometheus.Registerer = registry
-	Gatherer prometheus.Gatherer   = registry
-	m        *metrics
-)
-
-type metrics struct {
-	status   *prometheus.CounterVec
-	duration *prometheus.HistogramVec
-	summary  *prometheus.SummaryVec
-}
-
-func init() {
-	m = &metrics{
-		status: prometheus.NewCounterVec(
-			prometheus.CounterOpts{
-				Name: "http_outbound_requests_status_total",
-				Help: "Counter of successful Outbound HTTP requests by status code.",
-			},
-			[]string{"method", "code"},
-		),
-		duration: prometheus.NewHistogramVec(
-			prometheus.HistogramOpts{
-				Name: "http_outbound_request_duration_seconds",
-				Help: "Histogram of latencies for Outbound HTTP requests.",
-				Buckets: []float64{
-					.001, .0025, .005, .0075,
-					.01, .025, .05, .075,
-					.1, .25, .5, .75,
-					1, 2.5, 5, 7.5,
-					10,
-				},
-			},
-			[]string{"method"},
-		),
-		summary: prometheus.NewSummaryVec(
-			prometheus.SummaryOpts{
-				Name:       "http_outbound_request_quantile",
-				Help:       "Summary of latencies for Outbound HTTP requests.",
-				Objectives: map[float64]float64{0.5: 0.05, 0.75: 0.025, 0.9: 0.01, 0.95: 0.005, 0.99: 0.001, 1.00: 0.0},
-				MaxAge:     7 * 24 * time.Hour,
-			},
-			[]string{"method"},
-		),
-	}
-	Registry.MustRegister(m.status, m.duration, m.summary)
+// defaults are the configured headers sent with every request, shared by a
+// client and its untracked copies.
+type defaults struct {
+	mutex      sync.RWMutex
+	templates  http.Header
+	values     http.Header
+	generation int
 }
 
 // Config ...
@@ -110,6 +83,9 @@ type Config struct {
 	// presented by the server and any host name in that certificate.
 	InsecureSkipVerify bool
 
+	// TLS configures client certificates, trusted CAs and pinning.
+	TLS TLS
+
 	// MaxConnsPerHost optionally limits the total number of
 	// connections per host, including connections in the dialing,
 	// active, and idle states. On limit violation, dials will block.
@@ -125,6 +101,30 @@ type Config struct {
 	// DefaultMaxIdleConnsPerHost is used.
 	MaxIdleConnsPerHost int
 
+	// Cookies configures cookie jars, there are none by default.
+	Cookies Cookies
+
+	// Auth, when User is set, authenticates every request with HTTP Basic
+	// or Digest authentication.
+	Auth Auth
+
+	// OAuth2, when TokenURL is set, adds an access token from the token
+	// endpoint to every request as "Authorization: Bearer".
+	OAuth2 OAuth2
+
+	// SigV4, as "service/region", signs every request with AWS Signature
+	// Version 4. Credentials are read from the standard environment
+	// variables or AWSProfile of the shared credentials file.
+	SigV4      string
+	AWSProfile string
+
+	// JWT, when Key is set, adds a freshly minted token to every request.
+	JWT JWT
+
+	// HMAC, when Secret is set, signs every request with an HMAC of a
+	// canonical string.
+	HMAC HMAC
+
 	Headers http.Header
 }
 
@@ -135,10 +135,13 @@ func New(conf Config) (*Client, error) {
 		return nil, err
 	}
 
+	config, err := tlsConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+
 	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: conf.InsecureSkipVerify,
-		},
+		TLSClientConfig:     config,
 		MaxConnsPerHost:     conf.MaxConnsPerHost,
 		MaxIdleConns:        conf.MaxIdleConns,
 		MaxIdleConnsPerHost: conf.MaxIdleConnsPerHost,
@@ -150,49 +153,221 @@ func New(conf Config) (*Client, error) {
 		Timeout:   conf.Timeout * time.Millisecond,
 	}
 
-	return &Client{client: client, url: uri, headers: conf.Headers}, nil
+	c := &Client{client: client, url: uri, defaults: &defaults{templates: conf.Headers, values: conf.Headers}}
+	if c.sessions, err = newSessions(conf.Cookies); err != nil {
+		return nil, err
+	} else if c.sessions != nil {
+		client.Jar = c.sessions.base
+	}
+	if conf.OAuth2.TokenURL != "" {
+		if c.token, err = newToken(conf.OAuth2); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Auth.User != "" {
+		user, password, digest, err := newAuth(conf.Auth)
+		if err != nil {
+			return nil, err
+		}
+		if c.digest = digest; digest == nil {
+			c.basic = url.UserPassword(user, password)
+		}
+	}
+	if conf.JWT.Key != "" {
+		signer, err := NewJWT(conf.JWT)
+		if err != nil {
+			return nil, err
+		}
+		c.AddSigner(signer)
+	}
+	if conf.HMAC.Secret != "" {
+		signer, err := NewHMAC(conf.HMAC)
+		if err != nil {
+			return nil, err
+		}
+		c.AddSigner(signer)
+	}
+	if conf.SigV4 != "" {
+		if c.sigv4, err = newSigV4(conf.SigV4, conf.AWSProfile); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// SetVariables expands {name} placeholders in the configured headers with
+// vars, e.g. "Authorization: Bearer {access_token}" after a setup request.
+func (c *Client) SetVariables(vars map[string]string) {
+	headers := http.Header{}
+	for k, vs := range c.defaults.templates {
+		for _, v := range vs {
+			headers.Add(k, Expand(v, vars))
+		}
+	}
+
+	c.defaults.mutex.Lock()
+	defer c.defaults.mutex.Unlock()
+	c.defaults.values = headers
+	c.defaults.generation++
+}
+
+// ShareHeaders makes c send the configured headers of other, as expanded
+// by SetVariables and refreshed, instead of its own.
+func (c *Client) ShareHeaders(other *Client) {
+	c.defaults = other.defaults
+}
+
+// Unconfigured returns a copy of header without the configured headers of
+// c, e.g. the header a request was sent with, to send it again.
+func (c *Client) Unconfigured(header http.Header) http.Header {
+	out := clone(header)
+	c.defaults.mutex.RLock()
+	for k := range c.defaults.values {
+		out.Del(k)
+	}
+	c.defaults.mutex.RUnlock()
+	return out
+}
+
+// Untracked returns a client sharing the connections and headers of c that
+// doesn't record its requests or refresh credentials, for requests that
+// aren't part of the run.
+func (c *Client) Untracked() *Client {
+	client := *c
+	client.recorder = nil
+	client.refresh = nil
+	return &client
 }
 
 // Do ...
 func (c *Client) Do(method string, rel *url.URL, headers http.Header, body io.Reader) (*Response, error) {
-	uri := c.url.ResolveReference(rel)
-	request, err := http.NewRequest(method, uri.String(), body)
+	return c.Send(&Request{Method: method, URL: rel, Header: headers, Body: body})
+}
+
+// Send sends request, resolving its URL against the URL of the client. The
+// fields of the request are available to the signers.
+func (c *Client) Send(r *Request) (*Response, error) {
+	uri := c.url.ResolveReference(r.URL)
+
+	// The body is buffered so the request can be signed and sent again with
+	// renewed credentials.
+	var buffered []byte
+	if r.Body != nil {
+		var err error
+		if buffered, err = ioutil.ReadAll(r.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	// A request sent again with renewed credentials is recorded once, with
+	// the result of its final attempt; the refreshes are counted apart.
+	// Every attempt is sent on a copy of the header of the request, the one
+	// of the final attempt is handed back with the configured headers and
+	// credentials added.
+	c.sending()
+	header := clone(r.Header)
+	response, result, generation, err := c.send(r, uri, header, buffered)
+	if (c.refresh != nil || c.token != nil || c.digest != nil) && err == nil && response != nil && c.renew(response, generation) {
+		header = clone(r.Header)
+		response, result, _, err = c.send(r, uri, header, buffered)
+	}
+	c.record(result)
+	r.Header = header
+	return response, err
+}
+
+func clone(header http.Header) http.Header {
+	if header == nil {
+		return http.Header{}
+	}
+	return header.Clone()
+}
+
+// send adds the configured headers to the request, signs it and sends it,
+// returning the result to record. The generation of the headers is
+// returned, to tell whether they were refreshed since.
+func (c *Client) send(r *Request, uri *url.URL, headers http.Header, body []byte) (*Response, Result, int, error) {
+	request, err := http.NewRequest(r.Method, uri.String(), bytes.NewReader(body))
 	if err != nil {
-		return nil, err
+		return nil, Result{Method: r.Method, Template: r.Template, Fields: r.Fields, Class: ErrorOther, End: time.Now()}, 0, err
 	}
 
-	for k, vs := range c.headers {
+	c.defaults.mutex.RLock()
+	generation := c.defaults.generation
+	for k, vs := range c.defaults.values {
 		for _, v := range vs {
 			headers.Add(k, v)
 		}
 	}
+	c.defaults.mutex.RUnlock()
+
+	if c.token != nil {
+		value, err := c.token.get(c.client, c.refreshed)
+		if err != nil {
+			result, err := unauthorized(request, r, err)
+			return nil, result, generation, err
+		}
+		headers.Set("Authorization", "Bearer "+value)
+	}
+
 	request.Header = headers
-	return c.do(request)
+	if c.basic != nil {
+		password, _ := c.basic.Password()
+		request.SetBasicAuth(c.basic.Username(), password)
+	}
+	if c.digest != nil {
+		request = c.digest.trace(request)
+	}
+	for _, signer := range c.signers {
+		if err := signer.Sign(request, body, r.Fields); err != nil {
+			result, err := unauthorized(request, r, err)
+			return nil, result, generation, err
+		}
+	}
+	if c.sigv4 != nil {
+		c.sigv4.sign(request, body, time.Now())
+	}
+	response, result, err := c.do(request, r)
+	return response, result, generation, err
+}
+
+// unauthorized returns the result of a request that wasn't sent as its
+// credentials couldn't be fetched or it couldn't be signed.
+func unauthorized(request *http.Request, r *Request, err error) (Result, error) {
+	result := Result{Method: request.Method, Template: r.Template, Fields: r.Fields, Class: ErrorAuth, End: time.Now(), Sent: request.ContentLength}
+	return result, &Error{Class: ErrorAuth, Err: err}
 }
 
-func (c *Client) do(request *http.Request) (*Response, error) {
+func (c *Client) do(request *http.Request, r *Request) (*Response, Result, error) {
+	t := &tracer{}
+	request = request.WithContext(httptrace.WithClientTrace(request.Context(), t.trace()))
+
 	start := time.Now()
 	resp, err := c.client.Do(request)
 	if err != nil {
-		m.status.WithLabelValues(strings.ToLower(request.Method), "0").Inc()
+		end := time.Now()
+		response := &Response{
+			Duration: end.Sub(start),
+			Timings:  t.timings(end),
+			Request:  request,
+		}
+		class := classify(err, t.gotConn())
+		result := Result{Method: request.Method, Template: r.Template, Fields: r.Fields, Class: class, Duration: response.Duration, Timings: response.Timings, End: end, Sent: request.ContentLength}
 		if err, ok := err.(*url.Error); ok {
-			return &Response{
-				Duration: time.Now().Sub(start),
-				Request:  request,
-			}, err.Unwrap()
+			return response, result, &Error{Class: class, Err: err.Unwrap()}
 		}
-		return &Response{
-			Duration: time.Now().Sub(start),
-			Request:  request,
-		}, err
+		return response, result, &Error{Class: class, Err: err}
 	}
 	defer resp.Body.Close()
-	m.status.WithLabelValues(strings.ToLower(request.Method), strconv.Itoa(resp.StatusCode)).Inc()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		end := time.Now()
+		class := classifyBody(err)
+		result := Result{Method: request.Method, Template: r.Template, Fields: r.Fields, Class: class, Duration: end.Sub(start), Timings: t.timings(end), End: end, Sent: request.ContentLength, Received: int64(len(body))}
+		return nil, result, &Error{Class: class, Err: err}
 	}
+	end := time.Now()
 	response := Response{
 		Status:           resp.Status,
 		StatusCode:       resp.StatusCode,
@@ -205,15 +380,56 @@ func (c *Client) do(request *http.Request) (*Response, error) {
 		TransferEncoding: resp.TransferEncoding,
 		Uncompressed:     resp.Uncompressed,
 		Trailer:          resp.Trailer,
-		Duration:         time.Now().Sub(start),
+		Duration:         end.Sub(start),
+		Timings:          t.timings(end),
 		Request:          request,
+		connection:       t.connection(),
 	}
-	m.duration.WithLabelValues(strings.ToLower(request.Method)).Observe(response.Duration.Seconds())
-	m.summary.WithLabelValues(strings.ToLower(request.Method)).Observe(response.Duration.Seconds())
-	return &response, nil
+	result := Result{Method: request.Method, Template: r.Template, Fields: r.Fields, StatusCode: resp.StatusCode, Duration: response.Duration, Timings: response.Timings, End: end, Sent: request.ContentLength, Received: int64(len(body))}
+	return &response, result, nil
+}
+
+// Rebase moves uri onto the scheme, host and base path of base, keeping its
+// own path and query. e.g. http://old:3000/get/1 onto http://new/v2 gives
+// http://new/v2/get/1.
+func Rebase(base, uri *url.URL) *url.URL {
+	rebased := *uri
+	rebased.Scheme = base.Scheme
+	rebased.Host = base.Host
+	rebased.User = base.User
+	rebased.Path = strings.TrimSuffix(base.Path, "/") + uri.Path
+	rebased.RawPath = ""
+	return &rebased
 }
 
 // InRange ...
 func InRange(code, a, b int) bool {
 	return a <= code && code < b
 }
+
+// MatchStatus reports whether code matches pattern, a comma separated list
+// of statuses such as "any", "2xx", "-2xx" (anything but 2xx) or "201".
+func MatchStatus(pattern string, code int) bool {
+	for _, p := range strings.Split(pattern, ",") {
+		p = strings.ToLower(strings.TrimSpace(p))
+		switch {
+		case p == "any":
+			return true
+		case len(p) == 3 && strings.HasSuffix(p, "xx"):
+			a := int(p[0]-'0') * 100
+			if InRange(code, a, a+100) {
+				return true
+			}
+		case len(p) == 4 && strings.HasPrefix(p, "-") && strings.HasSuffix(p, "xx"):
+			a := int(p[1]-'0') * 100
+			if !InRange(code, a, a+100) {
+				return true
+			}
+		default:
+			if status, err := strconv.Atoi(p); err == nil && status == code {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,359 @@
+package http
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Cookies configures the cookie jars of a client.
+type Cookies struct {
+	// Mode is off, shared (one jar for every request) or per-row (a jar
+	// for each input row, starting from the cookies of the shared one).
+	Mode string
+
+	// File is a Netscape cookie file loaded before the run and replaced by
+	// the final cookies after it. It implies shared when Mode is off.
+	File string
+}
+
+// sessions keeps the jars of a client. With a cookie file, the jars of the
+// rows are merged into finished as the rows finish, to save them after the
+// run.
+type sessions struct {
+	mutex    sync.Mutex
+	file     string
+	perRow   bool
+	base     *Jar
+	finished *Jar
+}
+
+func newSessions(conf Cookies) (*sessions, error) {
+	switch conf.Mode {
+	case "", "off":
+		if conf.File == "" {
+			return nil, nil
+		}
+		conf.Mode = "shared"
+	case "shared", "per-row":
+	default:
+		return nil, fmt.Errorf("cookies: unknown mode %q, expected shared, per-row or off", conf.Mode)
+	}
+
+	s := &sessions{file: conf.File, perRow: conf.Mode == "per-row", base: NewJar(), finished: NewJar()}
+	if conf.File != "" {
+		if err := s.base.Load(conf.File); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("cookies: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// Session returns the client to send the requests of one input row with,
+// to Close once the row is done. In per-row mode it has a jar of its own,
+// with a copy of the cookies of c.
+func (c *Client) Session() *Client {
+	if c.sessions == nil || !c.sessions.perRow {
+		return c
+	}
+	client := *c
+	client.jar = c.sessions.base.Clone()
+	hc := *c.client
+	hc.Jar = client.jar
+	client.client = &hc
+	return &client
+}
+
+// Close ends the session of a row, keeping its cookies for the cookie file
+// if there is one.
+func (c *Client) Close() {
+	if c.jar == nil || c.sessions.file == "" {
+		return
+	}
+	c.sessions.mutex.Lock()
+	c.sessions.finished.merge(c.jar)
+	c.sessions.mutex.Unlock()
+}
+
+// SaveCookies writes the cookies of every jar of the client to the cookie
+// file, if there is one. Rows are merged in the order they finished.
+func (c *Client) SaveCookies() error {
+	if c.sessions == nil || c.sessions.file == "" {
+		return nil
+	}
+	jar := c.sessions.base.Clone()
+	c.sessions.mutex.Lock()
+	jar.merge(c.sessions.finished)
+	c.sessions.mutex.Unlock()
+	return jar.Save(c.sessions.file)
+}
+
+// Jar is a cookie jar whose cookies can be listed, loaded from and saved to
+// Netscape cookie files. Cookies may be set for the parent domains of a
+// host, but not for public suffixes such as com or co.uk, nor for those of
+// an IP address.
+type Jar struct {
+	mutex   sync.Mutex
+	entries map[string]*entry
+	next    int
+}
+
+type entry struct {
+	cookie   http.Cookie
+	hostOnly bool
+	created  int
+}
+
+// NewJar ...
+func NewJar() *Jar {
+	return &Jar{entries: make(map[string]*entry)}
+}
+
+func key(c *http.Cookie) string {
+	return c.Domain + ";" + c.Path + ";" + c.Name
+}
+
+// SetCookies implements http.CookieJar.
+func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	host := strings.ToLower(u.Hostname())
+	ip := net.ParseIP(host) != nil
+	now := time.Now()
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	for _, c := range cookies {
+		e := &entry{cookie: *c, created: j.next}
+		j.next++
+		domain := strings.TrimPrefix(strings.ToLower(c.Domain), ".")
+		switch {
+		case domain == "":
+			e.cookie.Domain, e.hostOnly = host, true
+		case host == domain && (ip || publicSuffix(domain)):
+			// IP addresses and public suffixes can only set cookies for
+			// themselves.
+			e.cookie.Domain, e.hostOnly = host, true
+		case !ip && (host == domain || strings.HasSuffix(host, "."+domain) && !publicSuffix(domain)):
+			e.cookie.Domain = domain
+		default:
+			continue
+		}
+		if !strings.HasPrefix(e.cookie.Path, "/") {
+			e.cookie.Path = defaultPath(u.Path)
+		}
+		switch {
+		case c.MaxAge < 0:
+			e.cookie.Expires = now
+		case c.MaxAge > 0:
+			e.cookie.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		e.cookie.MaxAge = 0
+
+		k := key(&e.cookie)
+		if !e.cookie.Expires.IsZero() && !e.cookie.Expires.After(now) {
+			delete(j.entries, k)
+			continue
+		}
+		if old, ok := j.entries[k]; ok {
+			e.created = old.created
+		}
+		j.entries[k] = e
+	}
+}
+
+// Cookies implements http.CookieJar.
+func (j *Jar) Cookies(u *url.URL) []*http.Cookie {
+	host := strings.ToLower(u.Hostname())
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	now := time.Now()
+
+	j.mutex.Lock()
+	matches := make([]*entry, 0)
+	for k, e := range j.entries {
+		if !e.cookie.Expires.IsZero() && !e.cookie.Expires.After(now) {
+			delete(j.entries, k)
+			continue
+		}
+		if e.hostOnly && host != e.cookie.Domain || !e.hostOnly && host != e.cookie.Domain && !strings.HasSuffix(host, "."+e.cookie.Domain) {
+			continue
+		}
+		if !pathMatch(path, e.cookie.Path) || e.cookie.Secure && u.Scheme != "https" {
+			continue
+		}
+		matches = append(matches, e)
+	}
+	j.mutex.Unlock()
+
+	// Longer paths first, then the oldest cookies first.
+	sort.Slice(matches, func(a, b int) bool {
+		if len(matches[a].cookie.Path) != len(matches[b].cookie.Path) {
+			return len(matches[a].cookie.Path) > len(matches[b].cookie.Path)
+		}
+		return matches[a].created < matches[b].created
+	})
+	cookies := make([]*http.Cookie, len(matches))
+	for i, e := range matches {
+		cookies[i] = &http.Cookie{Name: e.cookie.Name, Value: e.cookie.Value}
+	}
+	return cookies
+}
+
+// Clone returns a jar with a copy of the cookies of j.
+func (j *Jar) Clone() *Jar {
+	clone := NewJar()
+	clone.merge(j)
+	return clone
+}
+
+func (j *Jar) merge(other *Jar) {
+	other.mutex.Lock()
+	defer other.mutex.Unlock()
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	for k, e := range other.entries {
+		copied := *e
+		copied.created += j.next
+		j.entries[k] = &copied
+	}
+	j.next += other.next
+}
+
+// Load adds the cookies of a Netscape cookie file to the jar.
+func (j *Jar) Load(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		if httpOnly {
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return fmt.Errorf("%s:%d: expected 7 tab separated fields", file, n)
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", file, n, err)
+		}
+		e := &entry{
+			cookie: http.Cookie{
+				Domain:   strings.TrimPrefix(strings.ToLower(fields[0]), "."),
+				Path:     fields[2],
+				Secure:   strings.EqualFold(fields[3], "TRUE"),
+				Name:     fields[5],
+				Value:    fields[6],
+				HttpOnly: httpOnly,
+			},
+			hostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			created:  j.next,
+		}
+		j.next++
+		if expires > 0 {
+			e.cookie.Expires = time.Unix(expires, 0)
+		}
+		j.entries[key(&e.cookie)] = e
+	}
+	return scanner.Err()
+}
+
+// Save writes the cookies of the jar to a Netscape cookie file. Session
+// cookies are written with an expiry of 0.
+func (j *Jar) Save(file string) error {
+	j.mutex.Lock()
+	entries := make([]*entry, 0, len(j.entries))
+	for _, e := range j.entries {
+		entries = append(entries, e)
+	}
+	j.mutex.Unlock()
+	sort.Slice(entries, func(a, b int) bool { return key(&entries[a].cookie) < key(&entries[b].cookie) })
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "# Netscape HTTP Cookie File")
+	now := time.Now()
+	for _, e := range entries {
+		c := e.cookie
+		if !c.Expires.IsZero() && !c.Expires.After(now) {
+			continue
+		}
+		domain, prefix := c.Domain, ""
+		if !e.hostOnly {
+			domain = "." + domain
+		}
+		if c.HttpOnly {
+			prefix = "#HttpOnly_"
+		}
+		var expires int64
+		if !c.Expires.IsZero() {
+			expires = c.Expires.Unix()
+		}
+		fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%d\t%s\t%s\n", prefix, domain, flag(!e.hostOnly), c.Path, flag(c.Secure), expires, c.Name, c.Value)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func flag(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// publicSuffix reports whether domain is a public suffix, e.g. com or co.uk,
+// that no host may share cookies through.
+func publicSuffix(domain string) bool {
+	if net.ParseIP(domain) != nil {
+		return false
+	}
+	suffix, _ := publicsuffix.PublicSuffix(domain)
+	return suffix == domain
+}
+
+// defaultPath is the directory of a request path, RFC 6265 section 5.1.4.
+func defaultPath(path string) string {
+	i := strings.LastIndex(path, "/")
+	if !strings.HasPrefix(path, "/") || i == 0 {
+		return "/"
+	}
+	return path[:i]
+}
+
+func pathMatch(path, cookie string) bool {
+	if path == cookie {
+		return true
+	}
+	if strings.HasPrefix(path, cookie) {
+		return strings.HasSuffix(cookie, "/") || path[len(cookie)] == '/'
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// Classes of failed requests, recorded under the error_class label of
+// http_outbound_requests_total and the error_class column.
+const (
+	ErrorDNS            = "dns"
+	ErrorRefused        = "connection_refused"
+	ErrorReset          = "connection_reset"
+	ErrorConnectTimeout = "connect_timeout"
+	ErrorHeaderTimeout  = "header_timeout"
+	ErrorBodyTimeout    = "body_timeout"
+	ErrorTLS            = "tls"
+	ErrorRedirects      = "too_many_redirects"
+	ErrorBody           = "body_read"
+	ErrorCanceled       = "canceled"
+	// ErrorAuth is a request that wasn't sent as its credentials couldn't be
+	// fetched or it couldn't be signed.
+	ErrorAuth  = "auth"
+	ErrorOther = "other"
+)
+
+// ErrorClasses are the classes of failed requests in the order they're
+// reported.
+var ErrorClasses = []string{
+	ErrorDNS, ErrorRefused, ErrorReset, ErrorConnectTimeout, ErrorHeaderTimeout, ErrorBodyTimeout,
+	ErrorTLS, ErrorRedirects, ErrorBody, ErrorCanceled, ErrorAuth, ErrorOther,
+}
+
+// Error is a failed request and the class of its failure.
+type Error struct {
+	Class string
+	Err   error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Classify returns the class of err, or "" when err is nil.
+func Classify(err error) string {
+	if err == nil {
+		return ""
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Class
+	}
+	return classify(err, false)
+}
+
+// classify returns the class of an error sending a request; connected
+// tells a timeout awaiting the headers from one dialing.
+func classify(err error, connected bool) string {
+	if errors.Is(err, context.Canceled) {
+		return ErrorCanceled
+	}
+	var dns *net.DNSError
+	if errors.As(err, &dns) {
+		return ErrorDNS
+	}
+	var timeout net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &timeout) && timeout.Timeout() {
+		if connected {
+			return ErrorHeaderTimeout
+		}
+		return ErrorConnectTimeout
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ErrorRefused
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrorReset
+	}
+	var record tls.RecordHeaderError
+	var authority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	if errors.As(err, &record) || errors.As(err, &authority) || errors.As(err, &hostname) || errors.As(err, &invalid) {
+		return ErrorTLS
+	}
+	// Alerts and pinning failures have no exported type.
+	if message := err.Error(); strings.Contains(message, "tls: ") || strings.Contains(message, "x509: ") {
+		return ErrorTLS
+	}
+	if strings.Contains(err.Error(), "stopped after") && strings.Contains(err.Error(), "redirects") {
+		return ErrorRedirects
+	}
+	return ErrorOther
+}
+
+// classifyBody returns the class of an error reading a response body.
+func classifyBody(err error) string {
+	if errors.Is(err, context.Canceled) {
+		return ErrorCanceled
+	}
+	var timeout net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &timeout) && timeout.Timeout() {
+		return ErrorBodyTimeout
+	}
+	return ErrorBody
+}
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DustyRat/post-it/internal/secret"
+)
+
+// HMAC configures signing requests with an HMAC of a canonical string.
+type HMAC struct {
+	// Secret is the key, or env:NAME / file:path to read it from.
+	Secret string
+
+	// Template is the canonical string. It may use the input columns and
+	// {method}, {path}, {query}, {host}, {url}, {timestamp},
+	// {timestamp_ms}, {body_hash} (hex of the hashed body) and
+	// {header:Name}. A literal \n is a newline.
+	Template string
+
+	// Algorithm is sha1, sha256 or sha512.
+	Algorithm string
+
+	// Encoding of the signature, hex or base64.
+	Encoding string
+
+	// Header is set to the signature and TimestampHeader, unless empty, to
+	// the {timestamp} that was signed.
+	Header          string
+	TimestampHeader string
+}
+
+type hmacSigner struct {
+	conf HMAC
+	key  []byte
+	hash func() hash.Hash
+}
+
+// NewHMAC ...
+func NewHMAC(conf HMAC) (Signer, error) {
+	key, err := secret.Read(conf.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("hmac: %w", err)
+	}
+
+	s := &hmacSigner{conf: conf, key: []byte(key)}
+	switch strings.ToLower(conf.Algorithm) {
+	case "sha1":
+		s.hash = sha1.New
+	case "", "sha256":
+		s.hash = sha256.New
+	case "sha512":
+		s.hash = sha512.New
+	default:
+		return nil, fmt.Errorf("hmac: unknown algorithm %q, expected sha1, sha256 or sha512", conf.Algorithm)
+	}
+	switch strings.ToLower(conf.Encoding) {
+	case "", "hex", "base64":
+	default:
+		return nil, fmt.Errorf("hmac: unknown encoding %q, expected hex or base64", conf.Encoding)
+	}
+	if s.conf.Template == "" {
+		s.conf.Template = `{method}\n{path}\n{timestamp}\n{body_hash}`
+	}
+	s.conf.Template = strings.Replace(s.conf.Template, `\n`, "\n", -1)
+	if s.conf.Header == "" {
+		s.conf.Header = "X-Signature"
+	}
+	return s, nil
+}
+
+// placeholder matches a {name} of the canonical string template.
+var placeholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// Sign ...
+func (s *hmacSigner) Sign(request *http.Request, body []byte, fields map[string]string) error {
+	now := time.Now()
+	h := s.hash()
+	h.Write(body)
+
+	path := request.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	values := map[string]string{
+		"method":       request.Method,
+		"path":         path,
+		"query":        request.URL.RawQuery,
+		"host":         request.URL.Host,
+		"url":          request.URL.String(),
+		"timestamp":    strconv.FormatInt(now.Unix(), 10),
+		"timestamp_ms": strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10),
+		"body_hash":    hex.EncodeToString(h.Sum(nil)),
+	}
+
+	// The placeholders are substituted in a single pass, in the order of the
+	// template, so a value is never expanded again.
+	canonical := placeholder.ReplaceAllStringFunc(s.conf.Template, func(match string) string {
+		name := match[1 : len(match)-1]
+		if strings.HasPrefix(name, "header:") {
+			return request.Header.Get(http.CanonicalHeaderKey(strings.TrimPrefix(name, "header:")))
+		}
+		if v, ok := values[name]; ok {
+			return v
+		}
+		if v, ok := fields[name]; ok {
+			return v
+		}
+		return match
+	})
+
+	mac := hmac.New(s.hash, s.key)
+	mac.Write([]byte(canonical))
+	sum := mac.Sum(nil)
+
+	signature := hex.EncodeToString(sum)
+	if strings.ToLower(s.conf.Encoding) == "base64" {
+		signature = base64.StdEncoding.EncodeToString(sum)
+	}
+	if s.conf.TimestampHeader != "" {
+		request.Header.Set(s.conf.TimestampHeader, values["timestamp"])
+	}
+	request.Header.Set(s.conf.Header, signature)
+	return nil
+}
@@ -0,0 +1,226 @@
+package http
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DustyRat/post-it/internal/secret"
+)
+
+// JWT configures minting a signed JWT for every request.
+type JWT struct {
+	// Key is a PEM private key file for RS256 and ES256, or a file of the
+	// secret for HS256. Either may be given as env:NAME or file:path.
+	Key string
+
+	// Algorithm is RS256, ES256 or HS256.
+	Algorithm string
+
+	// KeyID, unless empty, is set as the kid of the token header.
+	KeyID string
+
+	// Claims are name=value pairs. Values may use {name} placeholders for
+	// input columns, "now", "now+5m" or "now-1h" give a NumericDate and
+	// name:=value sets raw JSON, e.g. admin:=true.
+	Claims []string
+
+	// Header the token is sent in, as a bearer token for Authorization.
+	Header string
+}
+
+type claim struct {
+	name  string
+	value string
+	raw   bool
+}
+
+type jwtSigner struct {
+	conf   JWT
+	claims []claim
+	key    interface{}
+}
+
+// NewJWT ...
+func NewJWT(conf JWT) (Signer, error) {
+	s := &jwtSigner{conf: conf}
+	if s.conf.Header == "" {
+		s.conf.Header = "Authorization"
+	}
+
+	for _, c := range conf.Claims {
+		i := strings.Index(c, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("jwt: claim %q is not name=value", c)
+		}
+		name, value, raw := c[:i], c[i+1:], false
+		if strings.HasSuffix(name, ":") {
+			name, raw = strings.TrimSuffix(name, ":"), true
+		}
+		s.claims = append(s.claims, claim{name: name, value: value, raw: raw})
+	}
+
+	var err error
+	switch conf.Algorithm {
+	case "HS256":
+		var key []byte
+		key, err = readKey(conf.Key)
+		s.key = bytes.TrimRight(key, "\r\n")
+	case "RS256", "ES256":
+		s.key, err = privateKey(conf.Key, conf.Algorithm)
+	default:
+		return nil, fmt.Errorf("jwt: unknown algorithm %q, expected RS256, ES256 or HS256", conf.Algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("jwt: %w", err)
+	}
+	return s, nil
+}
+
+// readKey reads a key given as env:NAME, file:path or the path of a file.
+func readKey(value string) ([]byte, error) {
+	if strings.HasPrefix(value, "env:") || strings.HasPrefix(value, "file:") {
+		key, err := secret.Read(value)
+		return []byte(key), err
+	}
+	return ioutil.ReadFile(value)
+}
+
+func privateKey(file, algorithm string) (interface{}, error) {
+	b, err := readKey(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("key is not PEM encoded")
+	}
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if algorithm == "RS256" {
+			return k, nil
+		}
+	case *ecdsa.PrivateKey:
+		if algorithm == "ES256" && k.Curve == elliptic.P256() {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("key doesn't match %s", algorithm)
+}
+
+// Sign ...
+func (s *jwtSigner) Sign(request *http.Request, body []byte, fields map[string]string) error {
+	token, err := s.mint(time.Now(), fields)
+	if err != nil {
+		return err
+	}
+	if http.CanonicalHeaderKey(s.conf.Header) == "Authorization" {
+		token = "Bearer " + token
+	}
+	request.Header.Set(s.conf.Header, token)
+	return nil
+}
+
+func (s *jwtSigner) mint(now time.Time, fields map[string]string) (string, error) {
+	header := map[string]string{"alg": s.conf.Algorithm, "typ": "JWT"}
+	if s.conf.KeyID != "" {
+		header["kid"] = s.conf.KeyID
+	}
+
+	claims := make(map[string]interface{}, len(s.claims))
+	for _, c := range s.claims {
+		value := Expand(c.value, fields)
+		switch {
+		case c.raw:
+			var v interface{}
+			if err := json.Unmarshal([]byte(value), &v); err != nil {
+				return "", fmt.Errorf("jwt: claim %s: %w", c.name, err)
+			}
+			claims[c.name] = v
+		case value == "now" || strings.HasPrefix(value, "now+") || strings.HasPrefix(value, "now-"):
+			var offset time.Duration
+			if value != "now" {
+				d, err := time.ParseDuration(value[3:])
+				if err != nil {
+					return "", fmt.Errorf("jwt: claim %s: %w", c.name, err)
+				}
+				offset = d
+			}
+			claims[c.name] = now.Add(offset).Unix()
+		default:
+			claims[c.name] = value
+		}
+	}
+
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	p, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	input := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(p)
+
+	signature, err := s.sign([]byte(input))
+	if err != nil {
+		return "", fmt.Errorf("jwt: %w", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func (s *jwtSigner) sign(input []byte) ([]byte, error) {
+	switch key := s.key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256(input)
+		return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(input)
+		r, ss, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		// JWS signatures are the fixed size big-endian R and S.
+		signature := make([]byte, 64)
+		rb, sb := r.Bytes(), ss.Bytes()
+		copy(signature[32-len(rb):32], rb)
+		copy(signature[64-len(sb):], sb)
+		return signature, nil
+	}
+	return nil, errors.New("no key")
+}
@@ -0,0 +1,149 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DustyRat/post-it/internal/secret"
+)
+
+// OAuth2 configures fetching access tokens from a token endpoint with the
+// client credentials grant, or the resource owner password grant when
+// Username is set. Client IDs, secrets, usernames and passwords may be given
+// as env:NAME or file:path.
+type OAuth2 struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	Username     string
+	Password     string
+}
+
+// expiryDelta renews tokens this long before they expire, so that they
+// don't expire in flight.
+const expiryDelta = 10 * time.Second
+
+// token caches the access token of a client, shared by its untracked copies.
+type token struct {
+	mutex  sync.Mutex
+	conf   OAuth2
+	value  string
+	expiry time.Time
+	issued bool
+}
+
+func newToken(conf OAuth2) (*token, error) {
+	var err error
+	for _, value := range []*string{&conf.ClientID, &conf.ClientSecret, &conf.Username, &conf.Password} {
+		if *value, err = secret.Read(*value); err != nil {
+			return nil, fmt.Errorf("oauth2: %w", err)
+		}
+	}
+	return &token{conf: conf}, nil
+}
+
+// get returns the cached token, fetching a new one when there is none or it
+// is about to expire.
+func (t *token) get(client *http.Client, refreshed func(result string)) (string, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.value != "" && (t.expiry.IsZero() || time.Now().Add(expiryDelta).Before(t.expiry)) {
+		return t.value, nil
+	}
+
+	value, expiry, err := t.fetch(client)
+	if t.issued {
+		if err != nil {
+			refreshed("failed")
+		} else {
+			refreshed("ok")
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("oauth2: %w", err)
+	}
+	t.value, t.expiry, t.issued = value, expiry, true
+	return t.value, nil
+}
+
+// invalidate drops the cached token if it is the one a request was rejected
+// with, so that the next request fetches a new one.
+func (t *token) invalidate(authorization string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if authorization == "Bearer "+t.value {
+		t.value = ""
+	}
+}
+
+func (t *token) fetch(client *http.Client) (string, time.Time, error) {
+	form := url.Values{}
+	if t.conf.Username != "" {
+		form.Set("grant_type", "password")
+		form.Set("username", t.conf.Username)
+		form.Set("password", t.conf.Password)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+	if len(t.conf.Scopes) > 0 {
+		form.Set("scope", strings.Join(t.conf.Scopes, " "))
+	}
+	if t.conf.ClientSecret == "" {
+		form.Set("client_id", t.conf.ClientID)
+	}
+
+	request, err := http.NewRequest(http.MethodPost, t.conf.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if t.conf.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(t.conf.ClientID), url.QueryEscape(t.conf.ClientSecret))
+	}
+
+	resp, err := client.Do(request)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	var result struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil && resp.StatusCode < 300 {
+		return "", time.Time{}, fmt.Errorf("token response: %w", err)
+	}
+	if resp.StatusCode >= 300 || result.Error != "" {
+		if result.Error != "" {
+			return "", time.Time{}, fmt.Errorf("token endpoint returned %d: %s %s", resp.StatusCode, result.Error, result.ErrorDescription)
+		}
+		return "", time.Time{}, fmt.Errorf("token endpoint returned %d", resp.StatusCode)
+	}
+	if result.AccessToken == "" {
+		return "", time.Time{}, errors.New("token response has no access_token")
+	}
+
+	var expiry time.Time
+	if result.ExpiresIn > 0 {
+		expiry = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	}
+	return result.AccessToken, expiry, nil
+}
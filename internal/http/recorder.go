@@ -0,0 +1,88 @@
+package http
+
+import "time"
+
+// Result is the outcome of a request sent by a client.
+type Result struct {
+	Method string
+	// Template is the URL of the request before its placeholders were
+	// expanded, e.g. http://localhost:3000/get/{id}.
+	Template string
+	// Fields are the values of the input row of the request.
+	Fields     map[string]string
+	StatusCode int
+	// Class is the class of the error of a failed request, see Classify.
+	Class    string
+	Duration time.Duration
+	Timings  Timings
+	End      time.Time
+
+	// Sent and Received are the sizes of the request and response bodies.
+	Sent     int64
+	Received int64
+}
+
+// Recorder records the result of every request sent by a client, see
+// Client.SetRecorder.
+type Recorder interface {
+	// Sending is called as a request is sent, its result is recorded once
+	// it's done.
+	Sending()
+	Record(result Result)
+}
+
+// RefreshRecorder is a Recorder that counts the credential refreshes of a
+// client as well: ok or failed.
+type RefreshRecorder interface {
+	Refreshed(result string)
+}
+
+// SetRecorder records the requests of c, and the copies made from it after,
+// with recorder. Untracked copies record nothing.
+func (c *Client) SetRecorder(recorder Recorder) {
+	c.recorder = recorder
+}
+
+func (c *Client) sending() {
+	if c.recorder != nil {
+		c.recorder.Sending()
+	}
+}
+
+func (c *Client) record(result Result) {
+	if c.recorder != nil {
+		c.recorder.Record(result)
+	}
+}
+
+func (c *Client) refreshed(result string) {
+	if r, ok := c.recorder.(RefreshRecorder); ok {
+		r.Refreshed(result)
+	}
+}
+
+// Recorders records with each of its recorders in turn.
+type Recorders []Recorder
+
+// Sending implements Recorder.
+func (r Recorders) Sending() {
+	for _, recorder := range r {
+		recorder.Sending()
+	}
+}
+
+// Record implements Recorder.
+func (r Recorders) Record(result Result) {
+	for _, recorder := range r {
+		recorder.Record(result)
+	}
+}
+
+// Refreshed implements RefreshRecorder.
+func (r Recorders) Refreshed(result string) {
+	for _, recorder := range r {
+		if refresh, ok := recorder.(RefreshRecorder); ok {
+			refresh.Refreshed(result)
+		}
+	}
+}
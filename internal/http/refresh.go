@@ -0,0 +1,66 @@
+package http
+
+import (
+	"log"
+	"net/http"
+	"sync"
+)
+
+// RefreshFunc fetches new credentials, e.g. by logging in again, and returns
+// the variables the configured headers are expanded with.
+type RefreshFunc func() (map[string]string, error)
+
+type refresh struct {
+	mutex  sync.Mutex
+	status string
+	fn     RefreshFunc
+}
+
+// SetRefresh makes the client call fn when a response status matches status,
+// eg: 401, 401,403 or 4xx, and send the request once more with the refreshed
+// headers. Requests failing at the same time wait for a single refresh
+// instead of starting their own.
+func (c *Client) SetRefresh(status string, fn RefreshFunc) {
+	c.refresh = &refresh{status: status, fn: fn}
+}
+
+// renew renews the credentials a response was rejected for and reports
+// whether the request should be sent again.
+func (c *Client) renew(response *Response, generation int) bool {
+	renewed := false
+	if c.token != nil && response.StatusCode == http.StatusUnauthorized {
+		c.token.invalidate(response.Request.Header.Get("Authorization"))
+		renewed = true
+	}
+	if c.digest != nil && response.StatusCode == http.StatusUnauthorized {
+		renewed = c.digest.challenged(response) || renewed
+	}
+	if c.refresh != nil && MatchStatus(c.refresh.status, response.StatusCode) {
+		renewed = c.refreshHeaders(generation) || renewed
+	}
+	return renewed
+}
+
+// refreshHeaders refreshes the configured headers unless they changed since
+// generation, and reports whether the request should be sent again.
+func (c *Client) refreshHeaders(generation int) bool {
+	c.refresh.mutex.Lock()
+	defer c.refresh.mutex.Unlock()
+
+	c.defaults.mutex.RLock()
+	current := c.defaults.generation
+	c.defaults.mutex.RUnlock()
+	if current != generation {
+		return true
+	}
+
+	vars, err := c.refresh.fn()
+	if err != nil {
+		c.refreshed("failed")
+		log.Printf("refresh: %s", err)
+		return false
+	}
+	c.SetVariables(vars)
+	c.refreshed("ok")
+	return true
+}
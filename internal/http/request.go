@@ -15,27 +15,40 @@ type Request struct {
 	URL      *url.URL
 	Body     io.Reader
 	Response *Response
+
+	// Fields are the values of the input row the request was built from.
+	Fields map[string]string
+
+	// Template is the URL of the request before Fields were expanded.
+	Template string
 }
 
 // NewRequest ...
 func NewRequest(method, rawurl string, header http.Header, body io.Reader, fields map[string]string) (*Request, error) {
-	for k, v := range fields {
-		rawurl = strings.Replace(rawurl, fmt.Sprintf("{%s}", k), v, 1)
-	}
-
-	uri, err := url.Parse(rawurl)
+	uri, err := url.Parse(Expand(rawurl, fields))
 	if err != nil {
 		return nil, err
 	}
 
 	return &Request{
-		Method: method,
-		Header: header,
-		URL:    uri,
-		Body:   body,
+		Method:   method,
+		Header:   header,
+		URL:      uri,
+		Body:     body,
+		Fields:   fields,
+		Template: rawurl,
 	}, nil
 }
 
+// Expand replaces every {name} placeholder in template with the value of
+// name in fields. Unknown placeholders are left as they are.
+func Expand(template string, fields map[string]string) string {
+	for k, v := range fields {
+		template = strings.Replace(template, fmt.Sprintf("{%s}", k), v, -1)
+	}
+	return template
+}
+
 // ParseHeaders ...
 func ParseHeaders(headers []string) http.Header {
 	header := http.Header{}
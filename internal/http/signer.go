@@ -0,0 +1,19 @@
+package http
+
+import (
+	"net/http"
+)
+
+// Signer signs requests, e.g. by adding a signature header. body is the
+// request body and fields are the values of the input row, or the variables
+// of a scenario step.
+type Signer interface {
+	Sign(request *http.Request, body []byte, fields map[string]string) error
+}
+
+// AddSigner adds a signer to the client. Signers run in the order they were
+// added, once every other header is set. AWS Signature Version 4, which
+// covers every header, always runs last.
+func (c *Client) AddSigner(signer Signer) {
+	c.signers = append(c.signers, signer)
+}
@@ -0,0 +1,247 @@
+package http
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sigv4 signs requests with AWS Signature Version 4.
+type sigv4 struct {
+	service     string
+	region      string
+	credentials awsCredentials
+}
+
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// newSigV4 parses "service/region", e.g. execute-api/us-east-1, and loads the
+// credentials from the standard environment variables or, failing that, the
+// shared credentials file.
+func newSigV4(scope, profile string) (*sigv4, error) {
+	parts := strings.Split(scope, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("aws-sigv4: %q is not service/region", scope)
+	}
+	credentials, err := loadAWSCredentials(profile)
+	if err != nil {
+		return nil, fmt.Errorf("aws-sigv4: %w", err)
+	}
+	return &sigv4{service: parts[0], region: parts[1], credentials: credentials}, nil
+}
+
+func loadAWSCredentials(profile string) (awsCredentials, error) {
+	if profile == "" {
+		if id, ok := os.LookupEnv("AWS_ACCESS_KEY_ID"); ok {
+			return awsCredentials{
+				AccessKeyID:     id,
+				SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+				SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			}, nil
+		}
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	file := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return awsCredentials{}, err
+		}
+		file = filepath.Join(home, ".aws", "credentials")
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	defer f.Close()
+
+	var credentials awsCredentials
+	found, section := false, ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			found = found || section == profile
+			continue
+		}
+		if section != profile {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(kv[1])
+		switch strings.TrimSpace(kv[0]) {
+		case "aws_access_key_id":
+			credentials.AccessKeyID = value
+		case "aws_secret_access_key":
+			credentials.SecretAccessKey = value
+		case "aws_session_token":
+			credentials.SessionToken = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return awsCredentials{}, err
+	}
+	if !found {
+		return awsCredentials{}, fmt.Errorf("profile %s not found in %s", profile, file)
+	}
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return awsCredentials{}, fmt.Errorf("profile %s in %s has no aws_access_key_id or aws_secret_access_key", profile, file)
+	}
+	return credentials, nil
+}
+
+// sign adds the X-Amz-Date and Authorization headers to request. Every header
+// already on the request is signed, so it must be the last one to change
+// them.
+func (s *sigv4) sign(request *http.Request, body []byte, now time.Time) {
+	now = now.UTC()
+	date := now.Format("20060102")
+	payload := sha256.Sum256(body)
+
+	request.Header.Del("Authorization")
+	request.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	if s.credentials.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", s.credentials.SessionToken)
+	}
+	if s.service == "s3" {
+		request.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payload[:]))
+	}
+
+	host := request.Host
+	if host == "" {
+		host = request.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	names := []string{"host"}
+	for name, values := range request.Header {
+		name = strings.ToLower(name)
+		if name == "user-agent" {
+			continue
+		}
+		trimmed := make([]string, len(values))
+		for i, v := range values {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		headers[name] = strings.Join(trimmed, ",")
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	canonical.WriteString(request.Method + "\n")
+	canonical.WriteString(s.path(request.URL) + "\n")
+	canonical.WriteString(query(request.URL) + "\n")
+	for _, name := range names {
+		canonical.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signed := strings.Join(names, ";")
+	canonical.WriteString("\n" + signed + "\n")
+	canonical.WriteString(hex.EncodeToString(payload[:]))
+
+	scope := strings.Join([]string{date, s.region, s.service, "aws4_request"}, "/")
+	hashed := sha256.Sum256([]byte(canonical.String()))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", now.Format("20060102T150405Z"), scope, hex.EncodeToString(hashed[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, s.service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.credentials.AccessKeyID, scope, signed, signature))
+}
+
+// path is the canonical URI of a request. Every service but S3 expects the
+// already escaped path to be escaped once more.
+func (s *sigv4) path(uri *url.URL) string {
+	path := uri.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if s.service == "s3" {
+		return path
+	}
+	return escape(path, false)
+}
+
+// query is the canonical query string of a request: its raw parameters
+// RFC 3986 encoded, sorted by key and then by value.
+func query(uri *url.URL) string {
+	pairs := make([][2]string, 0)
+	for _, param := range strings.Split(uri.RawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		key, value := param, ""
+		if i := strings.Index(param, "="); i >= 0 {
+			key, value = param[:i], param[i+1:]
+		}
+		pairs = append(pairs, [2]string{escape(unescape(key), true), escape(unescape(value), true)})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+
+	joined := make([]string, len(pairs))
+	for i, pair := range pairs {
+		joined[i] = pair[0] + "=" + pair[1]
+	}
+	return strings.Join(joined, "&")
+}
+
+// unescape decodes the percent-encoding of a raw query parameter, leaving
+// a + as it is, and s itself when it isn't valid.
+func unescape(s string) string {
+	if u, err := url.PathUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
+
+// escape percent-encodes everything but unreserved characters, and slashes
+// unless slash is set.
+func escape(s string, slash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !slash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,141 @@
+package http
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/DustyRat/post-it/internal/secret"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// TLS configures client certificates, trusted CAs and certificate pinning.
+type TLS struct {
+	// Cert and Key are PEM files of a client certificate and its private
+	// key. A .p12 or .pfx Cert is read as PKCS#12 with Password, which may
+	// be given as env:NAME or file:path, and needs no Key.
+	Cert     string
+	Key      string
+	Password string
+
+	// CACert is a PEM bundle of the CAs trusted instead of the system ones.
+	CACert string
+
+	// MinVersion is the lowest TLS version accepted, 1.0 to 1.3.
+	MinVersion string
+
+	// ServerName overrides the host name sent for SNI and verified.
+	ServerName string
+
+	// Pins are base64 SHA-256 hashes of the subject public key info, with or
+	// without a sha256// prefix. The server must present a certificate
+	// matching one of them, even when verification is skipped.
+	Pins []string
+}
+
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func tlsConfig(conf Config) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: conf.InsecureSkipVerify,
+		ServerName:         conf.TLS.ServerName,
+	}
+
+	if conf.TLS.MinVersion != "" {
+		version, ok := versions[conf.TLS.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("tls: unknown version %q, expected 1.0, 1.1, 1.2 or 1.3", conf.TLS.MinVersion)
+		}
+		config.MinVersion = version
+	}
+
+	if conf.TLS.Cert != "" {
+		certificate, err := certificate(conf.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("tls: %s: %w", conf.TLS.Cert, err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	if conf.TLS.CACert != "" {
+		b, err := ioutil.ReadFile(conf.TLS.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("tls: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("tls: %s: no PEM certificates found", conf.TLS.CACert)
+		}
+		config.RootCAs = pool
+	}
+
+	if len(conf.TLS.Pins) > 0 {
+		pins := make(map[string]bool)
+		for _, pin := range conf.TLS.Pins {
+			for _, p := range strings.Split(pin, ";") {
+				pins[strings.TrimPrefix(strings.TrimSpace(p), "sha256//")] = true
+			}
+		}
+		config.VerifyPeerCertificate = func(raw [][]byte, _ [][]*x509.Certificate) error {
+			for _, b := range raw {
+				cert, err := x509.ParseCertificate(b)
+				if err != nil {
+					return err
+				}
+				sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+				if pins[base64.StdEncoding.EncodeToString(sum[:])] {
+					return nil
+				}
+			}
+			return errors.New("tls: no certificate matches the pinned public keys")
+		}
+	}
+	return config, nil
+}
+
+func certificate(conf TLS) (tls.Certificate, error) {
+	switch strings.ToLower(filepath.Ext(conf.Cert)) {
+	case ".p12", ".pfx":
+		b, err := ioutil.ReadFile(conf.Cert)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+		password, err := secret.Read(conf.Password)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+		blocks, err := pkcs12.ToPEM(b, password)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+		var certs, key []byte
+		for _, block := range blocks {
+			if block.Type == "CERTIFICATE" {
+				certs = append(certs, pem.EncodeToMemory(block)...)
+			} else {
+				key = append(key, pem.EncodeToMemory(block)...)
+			}
+		}
+		return tls.X509KeyPair(certs, key)
+	}
+
+	key := conf.Key
+	if key == "" {
+		// The key may be in the same file as the certificate.
+		key = conf.Cert
+	}
+	return tls.LoadX509KeyPair(conf.Cert, key)
+}
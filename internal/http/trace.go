@@ -0,0 +1,108 @@
+package http
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings break the duration of a request down into its phases. DNS,
+// Connect and TLS are zero when the connection was reused.
+type Timings struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+
+	// TTFB is the time from the request being written to the first byte of
+	// the response, i.e. the server think time.
+	TTFB time.Duration
+
+	// Transfer is the time from the first byte of the response to the end
+	// of its body.
+	Transfer time.Duration
+
+	Reused bool
+}
+
+// tracer records the timestamps of a request. The hooks of a dial may run
+// after the request was given up on, so they are guarded by a mutex.
+type tracer struct {
+	mutex                  sync.Mutex
+	dnsStart, dnsDone      time.Time
+	connectStart, connDone time.Time
+	tlsStart, tlsDone      time.Time
+	wrote, firstByte       time.Time
+	reused, connected      bool
+	conn                   string
+}
+
+func (t *tracer) set(at *time.Time) {
+	t.mutex.Lock()
+	*at = time.Now()
+	t.mutex.Unlock()
+}
+
+func (t *tracer) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.set(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.set(&t.dnsDone) },
+		ConnectStart: func(string, string) {
+			t.mutex.Lock()
+			// Several addresses may be dialed, the first start counts.
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+			t.mutex.Unlock()
+		},
+		ConnectDone:       func(string, string, error) { t.set(&t.connDone) },
+		TLSHandshakeStart: func() { t.set(&t.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { t.set(&t.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mutex.Lock()
+			t.reused, t.connected = info.Reused, true
+			t.conn = connection(info.Conn)
+			t.mutex.Unlock()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.set(&t.wrote) },
+		GotFirstResponseByte: func() { t.set(&t.firstByte) },
+	}
+}
+
+// timings returns the phases of the request, which ended at end.
+func (t *tracer) timings(end time.Time) Timings {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	timings := Timings{
+		DNS:     between(t.dnsStart, t.dnsDone),
+		Connect: between(t.connectStart, t.connDone),
+		TLS:     between(t.tlsStart, t.tlsDone),
+		TTFB:    between(t.wrote, t.firstByte),
+		Reused:  t.reused,
+	}
+	if !t.firstByte.IsZero() {
+		timings.Transfer = between(t.firstByte, end)
+	}
+	return timings
+}
+
+// gotConn reports whether the request got a connection.
+func (t *tracer) gotConn() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.connected
+}
+
+// connection identifies the connection the request got, empty if none.
+func (t *tracer) connection() string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.conn
+}
+
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
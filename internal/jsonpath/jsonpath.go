@@ -0,0 +1,196 @@
+package jsonpath
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Path is a parsed JSONPath expression. Supported syntax is a subset of
+// JSONPath: "$.a.b", "$['a b']", "$.items[0]", "$.items[*].id", "$.*" and
+// recursive descent "$..updated_at".
+type Path struct {
+	expression string
+	steps      []step
+}
+
+type step struct {
+	key       string
+	wildcard  bool
+	recursive bool
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$-]*$`)
+
+// Parse ...
+func Parse(expression string) (*Path, error) {
+	e := strings.TrimSpace(expression)
+	if !strings.HasPrefix(e, "$") {
+		return nil, fmt.Errorf("invalid JSONPath %q: must start with $", expression)
+	}
+	e = e[1:]
+
+	steps := make([]step, 0)
+	for len(e) > 0 {
+		recursive := false
+		switch {
+		case strings.HasPrefix(e, ".."):
+			recursive = true
+			e = e[2:]
+		case strings.HasPrefix(e, "."):
+			e = e[1:]
+		case strings.HasPrefix(e, "["):
+		default:
+			return nil, fmt.Errorf("invalid JSONPath %q: unexpected %q", expression, e)
+		}
+
+		if strings.HasPrefix(e, "[") {
+			end := strings.Index(e, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q: missing ]", expression)
+			}
+			key := strings.TrimSpace(e[1:end])
+			e = e[end+1:]
+			if key == "*" {
+				steps = append(steps, step{wildcard: true, recursive: recursive})
+				continue
+			}
+			if unquoted, err := strconv.Unquote(strings.Replace(key, "'", "\"", -1)); err == nil {
+				key = unquoted
+			} else if _, err := strconv.Atoi(key); err != nil {
+				return nil, fmt.Errorf("invalid JSONPath %q: bad index %q", expression, key)
+			}
+			steps = append(steps, step{key: key, recursive: recursive})
+			continue
+		}
+
+		end := strings.IndexAny(e, ".[")
+		if end < 0 {
+			end = len(e)
+		}
+		key := e[:end]
+		e = e[end:]
+		if key == "" {
+			return nil, fmt.Errorf("invalid JSONPath %q: empty name", expression)
+		}
+		steps = append(steps, step{key: key, wildcard: key == "*", recursive: recursive})
+	}
+	return &Path{expression: expression, steps: steps}, nil
+}
+
+// MustParse ...
+func MustParse(expression string) *Path {
+	p, err := Parse(expression)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (p *Path) String() string {
+	return p.expression
+}
+
+// Match reports whether the path selects the location given by keys, a list
+// of object keys and array indexes from the root of a document.
+func (p *Path) Match(keys []string) bool {
+	return match(p.steps, keys)
+}
+
+func match(steps []step, keys []string) bool {
+	if len(steps) == 0 {
+		return len(keys) == 0
+	}
+	s := steps[0]
+	if s.recursive {
+		for i := range keys {
+			if (s.wildcard || s.key == keys[i]) && match(steps[1:], keys[i+1:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(keys) == 0 {
+		return false
+	}
+	return (s.wildcard || s.key == keys[0]) && match(steps[1:], keys[1:])
+}
+
+// Find returns every value in doc selected by the path.
+func (p *Path) Find(doc interface{}) []interface{} {
+	return find(p.steps, doc, make([]interface{}, 0))
+}
+
+// Get returns the first value selected by the path.
+func (p *Path) Get(doc interface{}) (interface{}, bool) {
+	values := p.Find(doc)
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
+
+func find(steps []step, node interface{}, out []interface{}) []interface{} {
+	if len(steps) == 0 {
+		return append(out, node)
+	}
+	s := steps[0]
+	for _, child := range children(node) {
+		if s.wildcard || s.key == child.key {
+			out = find(steps[1:], child.value, out)
+		}
+		if s.recursive {
+			out = find(steps, child.value, out)
+		}
+	}
+	return out
+}
+
+type child struct {
+	key   string
+	value interface{}
+}
+
+func children(node interface{}) []child {
+	out := make([]child, 0)
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for _, key := range Keys(n) {
+			out = append(out, child{key: key, value: n[key]})
+		}
+	case []interface{}:
+		for i, value := range n {
+			out = append(out, child{key: strconv.Itoa(i), value: value})
+		}
+	}
+	return out
+}
+
+// Format renders a location, as passed to Match, as a JSONPath expression.
+func Format(keys []string, indexes []bool) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for i, key := range keys {
+		switch {
+		case i < len(indexes) && indexes[i]:
+			fmt.Fprintf(&b, "[%s]", key)
+		case identifier.MatchString(key):
+			fmt.Fprintf(&b, ".%s", key)
+		default:
+			fmt.Fprintf(&b, "[%s]", strconv.Quote(key))
+		}
+	}
+	return b.String()
+}
+
+// Keys returns the keys of an object in sorted order.
+func Keys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
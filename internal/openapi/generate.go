@@ -0,0 +1,213 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var serverVariable = regexp.MustCompile(`\{([^}]+)\}`)
+
+// Template returns the post-it URL template for an operation, e.g.
+// "http://localhost:3000/orders/{order_id}?limit={limit}". Path and query
+// parameters become {column} placeholders.
+func (s *Spec) Template(operation *Operation) string {
+	base := ""
+	if servers, _ := s.root["servers"].([]interface{}); len(servers) > 0 {
+		if server, ok := servers[0].(map[string]interface{}); ok {
+			base, _ = server["url"].(string)
+			variables, _ := server["variables"].(map[string]interface{})
+			base = serverVariable.ReplaceAllStringFunc(base, func(match string) string {
+				variable, _ := variables[strings.Trim(match, "{}")].(map[string]interface{})
+				if value, ok := variable["default"]; ok {
+					return fmt.Sprint(value)
+				}
+				return match
+			})
+		}
+	}
+
+	query := make([]string, 0)
+	for _, p := range operation.Parameters {
+		if p.In == "query" {
+			query = append(query, fmt.Sprintf("%s={%s}", p.Name, p.Name))
+		}
+	}
+
+	template := strings.TrimSuffix(base, "/") + operation.Path
+	if len(query) > 0 {
+		template += "?" + strings.Join(query, "&")
+	}
+	return template
+}
+
+// Columns returns the input CSV header for an operation: every path and query
+// parameter followed by request_body when the operation accepts a body.
+func (s *Spec) Columns(operation *Operation) []string {
+	columns := make([]string, 0)
+	for _, p := range operation.Parameters {
+		if p.In == "path" || p.In == "query" {
+			columns = append(columns, p.Name)
+		}
+	}
+	if operation.RequestBody != nil {
+		columns = append(columns, "request_body")
+	}
+	return columns
+}
+
+// Rows synthesizes n input rows for an operation from the examples, defaults
+// and enums of its parameters and request body.
+func (s *Spec) Rows(operation *Operation, n int) [][]string {
+	rows := make([][]string, 0, n)
+	for i := 0; i < n; i++ {
+		row := make([]string, 0)
+		for _, p := range operation.Parameters {
+			if p.In != "path" && p.In != "query" {
+				continue
+			}
+			value := p.Example
+			if value == nil {
+				value = s.Example(p.Schema, p.Name, i)
+			}
+			row = append(row, scalar(value))
+		}
+		if operation.RequestBody != nil {
+			row = append(row, s.body(operation, i))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (s *Spec) body(operation *Operation, i int) string {
+	content, _ := operation.RequestBody["content"].(map[string]interface{})
+	mediaType, ok := media(content, "")
+	if !ok {
+		return ""
+	}
+	node, _ := s.resolve(mediaType).(map[string]interface{})
+	value, ok := node["example"]
+	if !ok {
+		value = s.Example(node["schema"], "", i)
+	}
+	if str, ok := value.(string); ok {
+		return str
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// Example builds a value for a schema, preferring its example, then its
+// default, then its enum. The i-th example cycles through enums and offsets
+// numbers and strings so that generated rows differ from each other.
+func (s *Spec) Example(node interface{}, name string, i int) interface{} {
+	return s.example(node, name, i, 0)
+}
+
+func (s *Spec) example(node interface{}, name string, i, depth int) interface{} {
+	m, ok := s.resolve(node).(map[string]interface{})
+	if !ok || depth > 16 {
+		return nil
+	}
+	if value, ok := m["example"]; ok {
+		return value
+	}
+	if value, ok := m["default"]; ok {
+		return value
+	}
+	if enum, ok := m["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[i%len(enum)]
+	}
+	for _, key := range []string{"allOf", "oneOf", "anyOf"} {
+		if list, ok := m[key].([]interface{}); ok && len(list) > 0 {
+			if key != "allOf" || len(list) == 1 {
+				return s.example(list[0], name, i, depth+1)
+			}
+			merged := make(map[string]interface{})
+			for _, sub := range list {
+				if object, ok := s.example(sub, name, i, depth+1).(map[string]interface{}); ok {
+					for k, v := range object {
+						merged[k] = v
+					}
+				}
+			}
+			return merged
+		}
+	}
+
+	t, _ := m["type"].(string)
+	if t == "" {
+		if _, ok := m["properties"]; ok {
+			t = "object"
+		}
+	}
+	switch t {
+	case "object":
+		object := make(map[string]interface{})
+		properties, _ := m["properties"].(map[string]interface{})
+		keys := make([]string, 0, len(properties))
+		for key := range properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			object[key] = s.example(properties[key], key, i, depth+1)
+		}
+		return object
+	case "array":
+		return []interface{}{s.example(m["items"], name, i, depth+1)}
+	case "integer", "number":
+		min, ok := m["minimum"].(float64)
+		if !ok {
+			min = 1
+		}
+		return min + float64(i)
+	case "boolean":
+		return i%2 == 0
+	case "string":
+		switch m["format"] {
+		case "date":
+			return "2020-01-01"
+		case "date-time":
+			return "2020-01-01T00:00:00Z"
+		case "uuid":
+			return fmt.Sprintf("00000000-0000-0000-0000-%012d", i+1)
+		case "email":
+			return fmt.Sprintf("user%d@example.com", i+1)
+		}
+		if name == "" {
+			name = "string"
+		}
+		return fmt.Sprintf("%s%d", name, i+1)
+	}
+	return nil
+}
+
+func scalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, scalar(item))
+		}
+		return strings.Join(values, ",")
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
@@ -0,0 +1,82 @@
+package openapi
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Report writes the contract coverage of the run: every operation that was
+// hit with its undocumented status codes and violations, the operations that
+// were never hit and requests that did not match any operation.
+func (s *Spec) Report(out io.Writer) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', tabwriter.AlignRight|tabwriter.Debug)
+	fmt.Fprintln(w, "OpenAPI")
+	fmt.Fprintln(w, "Operation \t Hits \t Undocumented \t Violations")
+	missed := make([]*Operation, 0)
+	for _, operation := range s.sorted() {
+		if operation.hits == 0 {
+			missed = append(missed, operation)
+			continue
+		}
+		fmt.Fprintln(w, fmt.Sprintf("%s \t %d \t %s \t %d", operation, operation.hits, counts(operation.undocumented), operation.violations))
+	}
+
+	if len(missed) > 0 {
+		fmt.Fprintln(w, "Never Hit")
+		for _, operation := range missed {
+			if operation.ID != "" {
+				fmt.Fprintln(w, fmt.Sprintf("%s \t %s", operation, operation.ID))
+			} else {
+				fmt.Fprintln(w, fmt.Sprintf("%s \t ", operation))
+			}
+		}
+	}
+
+	if len(s.unmatched) > 0 {
+		fmt.Fprintln(w, "Unmatched Requests \t Count")
+		keys := make([]string, 0, len(s.unmatched))
+		for key := range s.unmatched {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintln(w, fmt.Sprintf("%s \t %d", key, s.unmatched[key]))
+		}
+	}
+	w.Flush()
+}
+
+func (s *Spec) sorted() []*Operation {
+	operations := make([]*Operation, len(s.Operations))
+	copy(operations, s.Operations)
+	sort.Slice(operations, func(i, j int) bool {
+		if operations[i].Path != operations[j].Path {
+			return operations[i].Path < operations[j].Path
+		}
+		return operations[i].Method < operations[j].Method
+	})
+	return operations
+}
+
+func counts(statuses map[int]int) string {
+	codes := make([]int, 0, len(statuses))
+	for code := range statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	out := make([]string, 0, len(codes))
+	for _, code := range codes {
+		out = append(out, fmt.Sprintf("%d (%d)", code, statuses[code]))
+	}
+	if len(out) == 0 {
+		return "-"
+	}
+	return strings.Join(out, ", ")
+}
@@ -0,0 +1,284 @@
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DustyRat/post-it/internal/file/data"
+	"github.com/DustyRat/post-it/internal/schema"
+)
+
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Spec is an OpenAPI 3 document loaded from a local JSON or YAML file.
+type Spec struct {
+	Operations []*Operation
+
+	root    map[string]interface{}
+	schema  *schema.Schema
+	servers []string
+
+	mutex     *sync.Mutex
+	unmatched map[string]int
+}
+
+// Operation ...
+type Operation struct {
+	ID          string
+	Method      string // e.g. "GET"
+	Path        string // e.g. "/orders/{order_id}"
+	Summary     string
+	Parameters  []Parameter
+	RequestBody map[string]interface{}
+	Responses   map[string]interface{}
+
+	segments []string
+	literals int
+
+	hits         int
+	statuses     map[int]int
+	undocumented map[int]int
+	violations   int
+}
+
+// Parameter ...
+type Parameter struct {
+	Name     string
+	In       string // "path", "query", "header" or "cookie"
+	Required bool
+	Schema   map[string]interface{}
+	Example  interface{}
+}
+
+// Load reads an OpenAPI 3 specification. Files ending in .yaml or .yml are
+// parsed as YAML, everything else as JSON.
+func Load(file string) (*Spec, error) {
+	var root map[string]interface{}
+	if err := data.ReadFile(file, &root); err != nil {
+		return nil, err
+	}
+
+	version, _ := root["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("%s: unsupported OpenAPI version %q", filepath.Base(file), version)
+	}
+
+	spec := &Spec{
+		root:      root,
+		schema:    schema.New(file, root, root),
+		mutex:     &sync.Mutex{},
+		unmatched: make(map[string]int),
+	}
+	spec.servers = spec.basePaths()
+
+	paths, _ := root["paths"].(map[string]interface{})
+	for path, item := range paths {
+		item, _ := spec.resolve(item).(map[string]interface{})
+		shared := spec.parameters(item["parameters"])
+		for _, method := range methods {
+			node, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			operation := &Operation{
+				Method:       strings.ToUpper(method),
+				Path:         path,
+				Parameters:   merge(shared, spec.parameters(node["parameters"])),
+				statuses:     make(map[int]int),
+				undocumented: make(map[int]int),
+			}
+			operation.ID, _ = node["operationId"].(string)
+			operation.Summary, _ = node["summary"].(string)
+			operation.RequestBody, _ = spec.resolve(node["requestBody"]).(map[string]interface{})
+			operation.Responses, _ = node["responses"].(map[string]interface{})
+			operation.segments = split(path)
+			for _, segment := range operation.segments {
+				if !isTemplate(segment) {
+					operation.literals++
+				}
+			}
+			spec.Operations = append(spec.Operations, operation)
+		}
+	}
+
+	// Prefer the most specific template when several match, e.g. /orders/new over /orders/{id}.
+	sort.SliceStable(spec.Operations, func(i, j int) bool {
+		a, b := spec.Operations[i], spec.Operations[j]
+		if a.literals != b.literals {
+			return a.literals > b.literals
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Method < b.Method
+	})
+	return spec, nil
+}
+
+// Operation looks up an operation by its operationId.
+func (s *Spec) Operation(id string) (*Operation, error) {
+	for _, operation := range s.Operations {
+		if operation.ID == id {
+			return operation, nil
+		}
+	}
+	return nil, fmt.Errorf("operation %q not found", id)
+}
+
+// Servers returns the server URLs declared by the specification.
+func (s *Spec) Servers() []string {
+	servers := make([]string, 0)
+	list, _ := s.root["servers"].([]interface{})
+	for _, server := range list {
+		if server, ok := server.(map[string]interface{}); ok {
+			if u, ok := server["url"].(string); ok {
+				servers = append(servers, u)
+			}
+		}
+	}
+	return servers
+}
+
+// Schema returns a validator for a schema node of the specification.
+func (s *Spec) Schema(node interface{}) *schema.Schema {
+	return s.schema.At(node)
+}
+
+// Resolve follows a local "$ref" such as "#/components/parameters/Limit".
+func (s *Spec) Resolve(node interface{}) interface{} {
+	return s.resolve(node)
+}
+
+func (s *Spec) resolve(node interface{}) interface{} {
+	for i := 0; i < 32; i++ {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return node
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#") {
+			return node
+		}
+		next, err := schema.Pointer(s.root, strings.TrimPrefix(ref, "#"))
+		if err != nil {
+			return node
+		}
+		node = next
+	}
+	return node
+}
+
+func (s *Spec) parameters(node interface{}) []Parameter {
+	parameters := make([]Parameter, 0)
+	list, _ := node.([]interface{})
+	for _, item := range list {
+		p, ok := s.resolve(item).(map[string]interface{})
+		if !ok {
+			continue
+		}
+		parameter := Parameter{Example: p["example"]}
+		parameter.Name, _ = p["name"].(string)
+		parameter.In, _ = p["in"].(string)
+		parameter.Required, _ = p["required"].(bool)
+		parameter.Schema, _ = p["schema"].(map[string]interface{})
+		parameters = append(parameters, parameter)
+	}
+	return parameters
+}
+
+// basePaths returns the path component of each server url, so that requests
+// to https://host/v1/orders match the template /orders.
+func (s *Spec) basePaths() []string {
+	paths := make([]string, 0)
+	for _, server := range s.Servers() {
+		u, err := url.Parse(server)
+		if err != nil {
+			continue
+		}
+		path := strings.TrimSuffix(u.Path, "/")
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// Match finds the operation for a request, returning the values of its path parameters.
+func (s *Spec) Match(method string, uri *url.URL) (*Operation, map[string]string, error) {
+	candidates := []string{uri.Path}
+	for _, base := range s.servers {
+		if strings.HasPrefix(uri.Path, base+"/") {
+			candidates = append(candidates, strings.TrimPrefix(uri.Path, base))
+		}
+	}
+
+	for _, path := range candidates {
+		segments := split(path)
+		for _, operation := range s.Operations {
+			if operation.Method != strings.ToUpper(method) {
+				continue
+			}
+			if values, ok := operation.match(segments); ok {
+				return operation, values, nil
+			}
+		}
+	}
+	return nil, nil, errors.New("no operation matches the request")
+}
+
+func (o *Operation) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(o.segments) {
+		return nil, false
+	}
+	values := make(map[string]string)
+	for i, segment := range o.segments {
+		if isTemplate(segment) {
+			if segments[i] == "" {
+				return nil, false
+			}
+			value, err := url.PathUnescape(segments[i])
+			if err != nil {
+				value = segments[i]
+			}
+			values[strings.Trim(segment, "{}")] = value
+		} else if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return values, true
+}
+
+func (o *Operation) String() string {
+	return fmt.Sprintf("%s %s", o.Method, o.Path)
+}
+
+func split(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+func isTemplate(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// merge overrides path level parameters with operation level ones.
+func merge(shared, own []Parameter) []Parameter {
+	parameters := make([]Parameter, 0, len(shared)+len(own))
+	for _, p := range shared {
+		overridden := false
+		for _, o := range own {
+			if o.Name == p.Name && o.In == p.In {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			parameters = append(parameters, p)
+		}
+	}
+	return append(parameters, own...)
+}
@@ -0,0 +1,221 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Exchange is a request sent to the service and the response it produced.
+// StatusCode is zero when no response was received.
+type Exchange struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+
+	StatusCode     int
+	ResponseHeader http.Header
+	ResponseBody   []byte
+}
+
+// Violation ...
+type Violation struct {
+	Location string // e.g. "query.limit", "request.body#/id", "response.body#/items/0"
+	Message  string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Location, v.Message)
+}
+
+// Validate checks an exchange against the operation it matches and records it
+// for the coverage report.
+func (s *Spec) Validate(e Exchange) []Violation {
+	operation, values, err := s.Match(e.Method, e.URL)
+	if err != nil {
+		s.mutex.Lock()
+		s.unmatched[fmt.Sprintf("%s %s", strings.ToUpper(e.Method), e.URL.Path)]++
+		s.mutex.Unlock()
+		return []Violation{{Location: "request", Message: err.Error()}}
+	}
+
+	violations := s.validateRequest(operation, e, values)
+	documented := true
+	if e.StatusCode != 0 {
+		var v []Violation
+		v, documented = s.validateResponse(operation, e)
+		violations = append(violations, v...)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	operation.hits++
+	operation.violations += len(violations)
+	if e.StatusCode != 0 {
+		operation.statuses[e.StatusCode]++
+		if !documented {
+			operation.undocumented[e.StatusCode]++
+		}
+	}
+	return violations
+}
+
+func (s *Spec) validateRequest(operation *Operation, e Exchange, values map[string]string) []Violation {
+	violations := make([]Violation, 0)
+	query := e.URL.Query()
+	for _, p := range operation.Parameters {
+		location := fmt.Sprintf("%s.%s", p.In, p.Name)
+
+		var raw []string
+		switch p.In {
+		case "path":
+			if v, ok := values[p.Name]; ok {
+				raw = []string{v}
+			}
+		case "query":
+			raw = query[p.Name]
+		case "header":
+			raw = e.Header.Values(p.Name)
+		default:
+			continue
+		}
+
+		if len(raw) == 0 {
+			if p.Required || p.In == "path" {
+				violations = append(violations, Violation{Location: location, Message: "missing required parameter"})
+			}
+			continue
+		}
+		if p.Schema == nil {
+			continue
+		}
+		for _, v := range s.Schema(p.Schema).ValidateValue(s.coerce(p.Schema, raw)) {
+			violations = append(violations, Violation{Location: location + strings.TrimPrefix(v.Path, "#"), Message: v.Message})
+		}
+	}
+
+	if operation.RequestBody == nil {
+		return violations
+	}
+	if len(e.Body) == 0 {
+		if required, _ := operation.RequestBody["required"].(bool); required {
+			violations = append(violations, Violation{Location: "request.body", Message: "missing required request body"})
+		}
+		return violations
+	}
+	content, _ := operation.RequestBody["content"].(map[string]interface{})
+	return append(violations, s.validateBody("request.body", content, e.Header.Get("Content-Type"), e.Body)...)
+}
+
+func (s *Spec) validateResponse(operation *Operation, e Exchange) ([]Violation, bool) {
+	response, ok := lookup(operation.Responses, e.StatusCode)
+	if !ok {
+		return []Violation{{Location: "response.status", Message: fmt.Sprintf("undocumented status code %d", e.StatusCode)}}, false
+	}
+	r, _ := s.resolve(response).(map[string]interface{})
+	content, _ := r["content"].(map[string]interface{})
+	if len(content) == 0 {
+		return nil, true
+	}
+	return s.validateBody("response.body", content, e.ResponseHeader.Get("Content-Type"), e.ResponseBody), true
+}
+
+func (s *Spec) validateBody(location string, content map[string]interface{}, contentType string, body []byte) []Violation {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	mediaType, ok := media(content, contentType)
+	if !ok {
+		return []Violation{{Location: location, Message: fmt.Sprintf("undocumented content type %q", contentType)}}
+	}
+	node, _ := s.resolve(mediaType).(map[string]interface{})
+	if node == nil || node["schema"] == nil {
+		return nil
+	}
+	// Only JSON bodies can be checked against a schema.
+	if contentType != "" && !strings.Contains(contentType, "json") {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []Violation{{Location: location, Message: "expected JSON, got a non-JSON body"}}
+	}
+	violations := make([]Violation, 0)
+	for _, v := range s.Schema(node["schema"]).ValidateValue(value) {
+		violations = append(violations, Violation{Location: location + v.Path, Message: v.Message})
+	}
+	return violations
+}
+
+// lookup finds the response for a status code, trying "404", then "4XX" and finally "default".
+func lookup(responses map[string]interface{}, code int) (interface{}, bool) {
+	status := strconv.Itoa(code)
+	for _, key := range []string{status, status[:1] + "XX", status[:1] + "xx", "default"} {
+		if response, ok := responses[key]; ok {
+			return response, true
+		}
+	}
+	return nil, false
+}
+
+// media picks the content entry for a Content-Type header, falling back to
+// wildcards and, when the header is missing, the first JSON media type.
+func media(content map[string]interface{}, contentType string) (interface{}, bool) {
+	if contentType == "" {
+		for key, value := range content {
+			if strings.Contains(key, "json") {
+				return value, true
+			}
+		}
+		for _, value := range content {
+			return value, true
+		}
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+	wildcard := strings.SplitN(mediaType, "/", 2)[0] + "/*"
+	for _, key := range []string{mediaType, wildcard, "*/*"} {
+		if value, ok := content[key]; ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// coerce converts raw parameter strings into the JSON type their schema expects.
+func (s *Spec) coerce(node interface{}, raw []string) interface{} {
+	m, _ := s.resolve(node).(map[string]interface{})
+	t, _ := m["type"].(string)
+	if t == "array" {
+		values := make([]interface{}, 0)
+		for _, r := range raw {
+			for _, v := range strings.Split(r, ",") {
+				values = append(values, s.coerce(m["items"], []string{v}))
+			}
+		}
+		return values
+	}
+
+	value := raw[0]
+	switch t {
+	case "integer", "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
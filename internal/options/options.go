@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/DustyRat/post-it/internal/http"
+	"github.com/DustyRat/post-it/internal/sink"
+	"github.com/DustyRat/post-it/internal/threshold"
 )
 
 // Options ...
@@ -14,22 +16,127 @@ type Options struct {
 	Latency   bool
 	Flags     Flags
 
+	// Percentiles of the latencies printed in the statistics, e.g. 99.9.
+	Percentiles []float64
+
+	// SummaryJSON, when set, gets every statistic of the run as JSON.
+	SummaryJSON string
+
+	// Thresholds fail the run when any of them is breached.
+	Thresholds []threshold.Threshold
+
 	Connections int
 	Client      http.Config
 
 	Headers     []string
 	RawUrl      string
 	RequestBody string
+	Schema      string
+	OpenAPI     string
+	Compare     Compare
+	Snapshot    Snapshot
+	IgnorePaths []string
+	Scenario    string
+	Setup       string
+	Teardown    string
+	Refresh     Refresh
+	Metrics     Metrics
+	Sinks       Sinks
+	Timeseries  Timeseries
+	Report      Report
+	Suite       Suite
 
 	Timeout            time.Duration
 	IdleTimeout        time.Duration
 	InsecureSkipVerify bool
+
+	// Safe verifies server certificates unless --insecure is given explicitly.
+	Safe bool
 }
 
 // Flags ...
 type Flags struct {
-	Status  string
-	Errors  bool
-	Headers bool
-	Body    bool
+	Status   string
+	Errors   bool
+	Headers  bool
+	Body     bool
+	Timings  bool
+	Schema   bool
+	OpenAPI  bool
+	Compare  bool
+	Snapshot bool
+	Scenario bool
+}
+
+// Compare ...
+type Compare struct {
+	URL     string
+	Headers []string
+}
+
+// Snapshot ...
+type Snapshot struct {
+	Dir    string
+	Mode   string
+	Key    string
+	Update bool
+}
+
+// Refresh ...
+type Refresh struct {
+	File   string
+	Status string
+}
+
+// Metrics ...
+type Metrics struct {
+	// Addr, when set, serves the metrics of the run at /metrics, e.g. :9100.
+	Addr string
+
+	// Grace keeps serving the metrics for a while after the run.
+	Grace time.Duration
+}
+
+// Sinks ...
+type Sinks struct {
+	// StatsD is the address of a StatsD server, e.g. localhost:8125.
+	StatsD    string
+	DogStatsD bool
+	Prefix    string
+
+	// Influx is an InfluxDB write endpoint or the path of a file.
+	Influx string
+
+	Config sink.Config
+}
+
+// Timeseries ...
+type Timeseries struct {
+	// File, when set, gets a row of the throughput and latency of every
+	// Interval of the run.
+	File     string
+	Interval time.Duration
+}
+
+// Report ...
+type Report struct {
+	// File, when set, gets a self-contained HTML report of the run.
+	File string
+
+	// Slowest is the number of slowest requests listed in the report.
+	Slowest int
+}
+
+// Suite ...
+type Suite struct {
+	// JUnit and TAP, when set, get a test case of every row or group.
+	JUnit string
+	TAP   string
+
+	// Name is the template of the names of the cases, e.g. {method} {id}.
+	Name string
+	// Group is the column whose rows make a single case.
+	Group string
+	// Status is the status a response must match to pass, e.g. 2xx.
+	Status string
 }
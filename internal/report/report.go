@@ -0,0 +1,223 @@
+// Package report writes a self-contained HTML report of a run, with inline
+// CSS and SVG charts so it can be attached and opened offline.
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/DustyRat/post-it/internal/hdr"
+	internal "github.com/DustyRat/post-it/internal/http"
+	"github.com/DustyRat/post-it/internal/stats"
+)
+
+// Run describes the run of a report.
+type Run struct {
+	Method      string
+	URL         string
+	Scenario    string
+	Input       string
+	Headers     []string
+	Rows        int
+	Connections int
+	Timeout     time.Duration
+	Started     time.Time
+	Elapsed     time.Duration
+}
+
+// Write writes the report of a run to path.
+func Write(path string, run Run, collector *stats.Collector, percentiles []float64) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	data := struct {
+		Run        Run
+		Config     [][2]string
+		Responses  stats.Table
+		Statistics stats.Table
+		Histogram  template.HTML
+		Curve      template.HTML
+		Throughput template.HTML
+		Latency    template.HTML
+		Errors     []failure
+		Slowest    stats.Table
+	}{
+		Run:        run,
+		Config:     config(run),
+		Responses:  stats.Responses(collector),
+		Statistics: stats.Statistics(collector, percentiles, run.Elapsed),
+		Histogram:  histogram(collector.Latency),
+		Curve:      curve(collector.Latency),
+		Errors:     failures(collector),
+		Slowest:    slowest(run.Headers, collector.Slowest()),
+	}
+	data.Throughput, data.Latency = timeline(collector.Timeline())
+
+	if err := page.Execute(file, data); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func config(run Run) [][2]string {
+	config := [][2]string{}
+	add := func(name, value string) {
+		if value != "" {
+			config = append(config, [2]string{name, value})
+		}
+	}
+	add("Method", run.Method)
+	add("URL", run.URL)
+	add("Scenario", run.Scenario)
+	add("Input", run.Input)
+	add("Rows", strconv.Itoa(run.Rows))
+	add("Connections", strconv.Itoa(run.Connections))
+	add("Timeout", run.Timeout.String())
+	add("Started", run.Started.Format(time.RFC1123))
+	add("Elapsed", run.Elapsed.Round(time.Millisecond).String())
+	return config
+}
+
+// histogram charts the latencies in 40 buckets up to the 99.9th percentile,
+// and a last one for the slower.
+func histogram(h *hdr.Histogram) template.HTML {
+	const n = 40
+	if h.Count() == 0 {
+		return ""
+	}
+	min, upper, max := h.Min(), h.Percentile(99.9), h.Max()
+	if upper <= min {
+		upper = min + time.Millisecond
+	}
+	step := (upper - min) / n
+	if step < 1 {
+		step = 1
+	}
+	p := plot{xmin: 0, xmax: n + 1, ylabel: count}
+	var below uint64
+	for i := 0; i < n; i++ {
+		bound := min + step*time.Duration(i+1)
+		total := h.CountAtOrBelow(bound)
+		p.bars = append(p.bars, bar{x0: float64(i), x1: float64(i + 1), y: float64(total - below), color: "#4a7fc1", title: fmt.Sprintf("≤ %s: %d", bound.Round(time.Microsecond), total-below)})
+		below = total
+	}
+	if rest := h.Count() - below; rest > 0 {
+		p.bars = append(p.bars, bar{x0: n, x1: n + 1, y: float64(rest), color: "#c14a4a", title: fmt.Sprintf("> %s: %d", (min + step*n).Round(time.Microsecond), rest)})
+	}
+	p.xticks = []tick{
+		{0, min.Round(time.Microsecond).String()},
+		{n / 2, (min + step*n/2).Round(time.Microsecond).String()},
+		{n, (min + step*n).Round(time.Microsecond).String()},
+	}
+	if max > min+step*n {
+		p.xticks = append(p.xticks, tick{n + 0.5, max.Round(time.Microsecond).String()})
+	}
+	return p.svg()
+}
+
+// curve charts the latency of every percentile, the x axis spreads the
+// nines: 90%, 99%, 99.9%...
+func curve(h *hdr.Histogram) template.HTML {
+	if h.Count() == 0 {
+		return ""
+	}
+	const nines = 4.0
+	ps := make([]float64, 0)
+	xs := make([]float64, 0)
+	for x := 0.0; x <= nines; x += 0.05 {
+		ps = append(ps, 100*(1-math.Pow(10, -x)))
+		xs = append(xs, x)
+	}
+	l := line{name: "latency", color: "#4a7fc1"}
+	for i, value := range h.Percentiles(ps...) {
+		l.points = append(l.points, [2]float64{xs[i], value.Seconds()})
+	}
+	p := plot{xmin: 0, xmax: nines, ylabel: duration, lines: []line{l}}
+	for _, t := range []string{"0%", "90%", "99%", "99.9%", "99.99%"} {
+		p.xticks = append(p.xticks, tick{float64(len(p.xticks)), t})
+	}
+	return p.svg()
+}
+
+// timeline charts the requests and latencies of each second.
+func timeline(seconds []stats.Second) (template.HTML, template.HTML) {
+	if len(seconds) == 0 {
+		return "", ""
+	}
+	throughput := plot{xmin: 0, xmax: float64(len(seconds)), ylabel: count}
+	latency := plot{xmin: 0, xmax: float64(len(seconds)), ylabel: duration}
+	mean := line{name: "average", color: "#4a7fc1"}
+	max := line{name: "max", color: "#c1904a"}
+	for i, second := range seconds {
+		x := float64(i)
+		throughput.bars = append(throughput.bars, bar{x0: x, x1: x + 1, y: float64(second.Requests), color: "#4a7fc1", title: fmt.Sprintf("%ds: %d requests", i, second.Requests)})
+		if second.Errors > 0 {
+			throughput.bars = append(throughput.bars, bar{x0: x, x1: x + 1, y: float64(second.Errors), color: "#c14a4a", title: fmt.Sprintf("%ds: %d errors", i, second.Errors)})
+		}
+		if ok := second.Requests - second.Errors; ok > 0 {
+			mean.points = append(mean.points, [2]float64{x + 0.5, (second.Sum / time.Duration(ok)).Seconds()})
+			max.points = append(max.points, [2]float64{x + 0.5, second.Max.Seconds()})
+		}
+	}
+	latency.lines = []line{max, mean}
+	ticks := []tick{{0, "0s"}, {float64(len(seconds)) / 2, fmt.Sprintf("%ds", len(seconds)/2)}, {float64(len(seconds)), fmt.Sprintf("%ds", len(seconds))}}
+	throughput.xticks, latency.xticks = ticks, ticks
+	return throughput.svg(), latency.svg()
+}
+
+// failure is the number of failed requests of an error class.
+type failure struct {
+	Class   string
+	Count   uint64
+	Percent float64
+}
+
+func failures(collector *stats.Collector) []failure {
+	errors := collector.Errors()
+	var total uint64
+	for _, count := range errors {
+		total += count
+	}
+	out := make([]failure, 0)
+	for _, class := range internal.ErrorClasses {
+		if count := errors[class]; count > 0 {
+			out = append(out, failure{Class: class, Count: count, Percent: float64(count) / float64(total) * 100})
+		}
+	}
+	return out
+}
+
+// slowest returns a table of the slowest results with their input fields.
+func slowest(headers []string, results []internal.Result) stats.Table {
+	if len(results) == 0 {
+		return nil
+	}
+	table := stats.Table{append([]string{"Duration", "Method", "Status"}, headers...)}
+	for _, result := range results {
+		status := strconv.Itoa(result.StatusCode)
+		if result.Class != "" {
+			status = result.Class
+		}
+		row := []string{result.Duration.Round(time.Microsecond).String(), result.Method, status}
+		for _, header := range headers {
+			row = append(row, result.Fields[header])
+		}
+		table = append(table, row)
+	}
+	return table
+}
+
+func count(v float64) string {
+	return strconv.FormatFloat(math.Round(v), 'f', -1, 64)
+}
+
+func duration(v float64) string {
+	return time.Duration(v * float64(time.Second)).Round(time.Microsecond).String()
+}
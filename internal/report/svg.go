@@ -0,0 +1,92 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strings"
+)
+
+// Dimensions of the charts, in pixels.
+const (
+	width  = 720.0
+	height = 240.0
+	left   = 72.0
+	right  = 16.0
+	top    = 12.0
+	bottom = 28.0
+)
+
+// tick is a labelled position on the x axis.
+type tick struct {
+	x     float64
+	label string
+}
+
+// line is a series of x, y points drawn as a line.
+type line struct {
+	name   string
+	color  string
+	points [][2]float64
+}
+
+// bar spans x0 to x1 from 0 to y.
+type bar struct {
+	x0, x1, y float64
+	color     string
+	title     string
+}
+
+// plot is a chart of lines and bars, with y from 0 to the highest value.
+type plot struct {
+	xmin, xmax float64
+	xticks     []tick
+	ylabel     func(float64) string
+	lines      []line
+	bars       []bar
+}
+
+func (p plot) svg() template.HTML {
+	var ymax float64
+	for _, l := range p.lines {
+		for _, point := range l.points {
+			ymax = math.Max(ymax, point[1])
+		}
+	}
+	for _, b := range p.bars {
+		ymax = math.Max(ymax, b.y)
+	}
+	if ymax == 0 {
+		ymax = 1
+	}
+	if p.xmax <= p.xmin {
+		p.xmax = p.xmin + 1
+	}
+	x := func(v float64) float64 { return left + (v-p.xmin)/(p.xmax-p.xmin)*(width-left-right) }
+	y := func(v float64) float64 { return height - bottom - v/ymax*(height-top-bottom) }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg viewBox="0 0 %.0f %.0f" xmlns="http://www.w3.org/2000/svg">`, width, height)
+	for i := 0; i <= 4; i++ {
+		v := ymax * float64(i) / 4
+		fmt.Fprintf(&b, `<line class="grid" x1="%.1f" x2="%.1f" y1="%.1f" y2="%.1f"/>`, left, width-right, y(v), y(v))
+		fmt.Fprintf(&b, `<text class="axis" x="%.1f" y="%.1f" text-anchor="end">%s</text>`, left-6, y(v)+4, template.HTMLEscapeString(p.ylabel(v)))
+	}
+	for _, t := range p.xticks {
+		fmt.Fprintf(&b, `<text class="axis" x="%.1f" y="%.1f" text-anchor="middle">%s</text>`, x(t.x), height-bottom+18, template.HTMLEscapeString(t.label))
+	}
+	for _, bar := range p.bars {
+		w := math.Max(x(bar.x1)-x(bar.x0)-1, 1)
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s</title></rect>`, x(bar.x0), y(bar.y), w, y(0)-y(bar.y), bar.color, template.HTMLEscapeString(bar.title))
+	}
+	for _, l := range p.lines {
+		points := make([]string, 0, len(l.points))
+		for _, point := range l.points {
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x(point[0]), y(point[1])))
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"><title>%s</title></polyline>`, l.color, strings.Join(points, " "), template.HTMLEscapeString(l.name))
+	}
+	fmt.Fprintf(&b, `<line class="frame" x1="%.1f" x2="%.1f" y1="%.1f" y2="%.1f"/>`, left, width-right, y(0), y(0))
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
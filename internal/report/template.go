@@ -0,0 +1,77 @@
+package report
+
+import "html/template"
+
+var page = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>post-it report{{with .Run.URL}}: {{.}}{{end}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #222; margin: 2em auto; max-width: 760px; padding: 0 1em; }
+h1 { font-size: 1.5em; margin-bottom: 0.2em; }
+.muted { color: #888; }
+h2 { font-size: 1.15em; margin-top: 2em; border-bottom: 1px solid #ddd; padding-bottom: 0.2em; }
+table { border-collapse: collapse; font-size: 0.85em; margin: 0.5em 0; }
+th, td { padding: 0.25em 0.7em; border-bottom: 1px solid #eee; text-align: right; white-space: nowrap; }
+th { background: #f5f5f5; }
+td:first-child, th:first-child { text-align: left; }
+table.config td:first-child { font-weight: bold; }
+table.config td { text-align: left; white-space: normal; word-break: break-all; }
+.scroll { overflow-x: auto; }
+svg { width: 100%; height: auto; }
+svg .grid { stroke: #eee; }
+svg .frame { stroke: #999; }
+svg .axis { font-size: 11px; fill: #666; }
+.legend span { display: inline-block; width: 0.8em; height: 0.8em; margin: 0 0.3em 0 1em; }
+.meter { background: #c14a4a; height: 0.8em; }
+</style>
+</head>
+<body>
+<h1>post-it report</h1>
+<div class="muted">{{.Run.Started.Format "2006-01-02 15:04:05 MST"}}</div>
+
+<h2>Configuration</h2>
+<table class="config">
+{{range .Config}}<tr><td>{{index . 0}}</td><td>{{index . 1}}</td></tr>
+{{end}}</table>
+
+<h2>Responses</h2>
+<div class="scroll"><table>
+{{range $i, $row := .Responses}}<tr>{{range $row}}{{if eq $i 0}}<th>{{.}}</th>{{else}}<td>{{.}}</td>{{end}}{{end}}</tr>
+{{end}}</table></div>
+
+<h2>Statistics</h2>
+<div class="scroll"><table>
+{{range $i, $row := .Statistics}}<tr>{{range $row}}{{if eq $i 0}}<th>{{.}}</th>{{else}}<td>{{.}}</td>{{end}}{{end}}</tr>
+{{end}}</table></div>
+{{with .Histogram}}
+<h2>Latency Histogram</h2>
+{{.}}
+<div class="legend"><span style="background:#4a7fc1"></span>up to the 99.9th percentile<span style="background:#c14a4a"></span>slower</div>
+{{end}}{{with .Curve}}
+<h2>Latency by Percentile</h2>
+{{.}}
+{{end}}{{with .Throughput}}
+<h2>Requests per Second</h2>
+{{.}}
+<div class="legend"><span style="background:#4a7fc1"></span>requests<span style="background:#c14a4a"></span>errors</div>
+{{end}}{{with .Latency}}
+<h2>Latency over Time</h2>
+{{.}}
+<div class="legend"><span style="background:#4a7fc1"></span>average<span style="background:#c1904a"></span>max</div>
+{{end}}{{with .Errors}}
+<h2>Errors</h2>
+<table>
+<tr><th>Class</th><th>Count</th><th>%</th><th></th></tr>
+{{range .}}<tr><td>{{.Class}}</td><td>{{.Count}}</td><td>{{printf "%.1f" .Percent}}</td><td style="width:200px"><div class="meter" style="width:{{printf "%.0f" .Percent}}%"></div></td></tr>
+{{end}}</table>
+{{end}}{{with .Slowest}}
+<h2>Slowest Requests</h2>
+<div class="scroll"><table>
+{{range $i, $row := .}}<tr>{{range $row}}{{if eq $i 0}}<th>{{.}}</th>{{else}}<td>{{.}}</td>{{end}}{{end}}</tr>
+{{end}}</table></div>
+{{end}}
+</body>
+</html>
+`))
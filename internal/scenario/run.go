@@ -0,0 +1,86 @@
+package scenario
+
+import (
+	"errors"
+
+	internal "github.com/DustyRat/post-it/internal/http"
+	"github.com/DustyRat/post-it/internal/stats"
+)
+
+// Result is the outcome of running a scenario for one input row.
+type Result struct {
+	Steps []*StepResult
+	Vars  map[string]string
+
+	// Failed is the step that ended the chain, nil when every step passed.
+	Failed *Step
+}
+
+// StepResult ...
+type StepResult struct {
+	Step     *Step
+	Request  *internal.Request
+	Body     []byte
+	Response *internal.Response
+	Err      error
+}
+
+// Last returns the result of the last step that ran.
+func (r *Result) Last() *StepResult {
+	return r.Steps[len(r.Steps)-1]
+}
+
+// Run executes the steps in order with the row's fields as variables and
+// records their results with collector. A failed step ends the chain, the
+// remaining steps are counted as skipped.
+func (s *Scenario) Run(client *internal.Client, collector *stats.Collector, fields map[string]string) *Result {
+	vars := make(map[string]string, len(fields))
+	for k, v := range fields {
+		vars[k] = v
+	}
+
+	result := &Result{Vars: vars}
+	for _, step := range s.Steps {
+		if result.Failed != nil {
+			collector.Stepped(step.index, step.Name, "skipped")
+			continue
+		}
+
+		r := step.Do(client, vars)
+		if r.Response != nil {
+			collector.StepLatency(step.index, step.Name, r.Response.Duration)
+		}
+		result.Steps = append(result.Steps, r)
+		if r.Err != nil {
+			result.Failed = step
+			collector.Stepped(step.index, step.Name, "failed")
+		} else {
+			collector.Stepped(step.index, step.Name, "ok")
+		}
+	}
+	return result
+}
+
+// Do sends the request of the step, checks the response and extracts its
+// variables into vars.
+func (s *Step) Do(client *internal.Client, vars map[string]string) *StepResult {
+	request, body, err := s.Request(vars)
+	if err != nil {
+		return &StepResult{Step: s, Err: err}
+	}
+	result := &StepResult{Step: s, Request: request, Body: body}
+
+	response, err := client.Send(request)
+	request.Response = response
+	result.Response = response
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if response == nil {
+		result.Err = errors.New("no response")
+		return result
+	}
+	result.Err = s.Check(response, vars)
+	return result
+}
@@ -0,0 +1,228 @@
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/DustyRat/post-it/internal/file/data"
+	internal "github.com/DustyRat/post-it/internal/http"
+	"github.com/DustyRat/post-it/internal/jsonpath"
+)
+
+// Scenario is an ordered list of steps that runs once per input row.
+type Scenario struct {
+	Steps []*Step `json:"steps"`
+}
+
+// Step is a single request of a scenario. URL, header values and the body
+// may use {name} placeholders for input columns and extracted variables.
+type Step struct {
+	Name    string            `json:"name"`
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+
+	// Expect is the status the response must have for the chain to
+	// continue, e.g. "2xx" or "200,201". Defaults to "2xx".
+	Expect string `json:"expect"`
+
+	// Extract maps variable names to a JSONPath into the response body
+	// ("$.id"), a response header ("header:Location") or a cookie set by
+	// the response ("cookie:session").
+	Extract map[string]string `json:"extract"`
+
+	// Assert maps a JSONPath into the response body to its expected value.
+	Assert map[string]string `json:"assert"`
+
+	index int
+	paths map[string]*jsonpath.Path
+}
+
+// Load reads a scenario from a JSON or YAML file.
+func Load(file string) (*Scenario, error) {
+	s := &Scenario{}
+	if err := data.ReadFile(file, s); err != nil {
+		return nil, err
+	}
+	if len(s.Steps) == 0 {
+		return nil, fmt.Errorf("%s: scenario has no steps", file)
+	}
+	for i, step := range s.Steps {
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("step %d", i+1)
+		}
+		if err := step.compile(i); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", file, step.Name, err)
+		}
+	}
+	return s, nil
+}
+
+// LoadStep reads a single step, such as a setup or teardown request, from a
+// JSON or YAML file.
+func LoadStep(file string) (*Step, error) {
+	s := &Step{}
+	if err := data.ReadFile(file, s); err != nil {
+		return nil, err
+	}
+	if s.Name == "" {
+		s.Name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+	if err := s.compile(0); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return s, nil
+}
+
+func (s *Step) compile(index int) error {
+	s.index = index
+	s.Method = strings.ToUpper(s.Method)
+	if s.Method == "" {
+		s.Method = http.MethodGet
+	}
+	if s.URL == "" {
+		return errors.New("missing url")
+	}
+	if s.Expect == "" {
+		s.Expect = "2xx"
+	}
+
+	s.paths = make(map[string]*jsonpath.Path)
+	for _, expression := range s.Extract {
+		if strings.HasPrefix(expression, "header:") || strings.HasPrefix(expression, "cookie:") {
+			continue
+		}
+		path, err := jsonpath.Parse(expression)
+		if err != nil {
+			return err
+		}
+		s.paths[expression] = path
+	}
+	for expression := range s.Assert {
+		path, err := jsonpath.Parse(expression)
+		if err != nil {
+			return err
+		}
+		s.paths[expression] = path
+	}
+	return nil
+}
+
+// Index is the position of the step in its scenario.
+func (s *Step) Index() int {
+	return s.index
+}
+
+// Request builds the request of the step for the given variables.
+func (s *Step) Request(vars map[string]string) (*internal.Request, []byte, error) {
+	header := http.Header{}
+	for k, v := range s.Headers {
+		header.Set(k, internal.Expand(v, vars))
+	}
+	body := []byte(internal.Expand(s.body(), vars))
+	request, err := internal.NewRequest(s.Method, s.URL, header, bytes.NewReader(body), vars)
+	if err != nil {
+		return nil, nil, err
+	}
+	return request, body, nil
+}
+
+// body returns the body template. A JSON string is used as is, any other
+// JSON value is sent as JSON.
+func (s *Step) body() string {
+	if len(s.Body) == 0 || string(s.Body) == "null" {
+		return ""
+	}
+	var str string
+	if err := json.Unmarshal(s.Body, &str); err == nil {
+		return str
+	}
+	return string(s.Body)
+}
+
+// Check verifies the response of the step and extracts its variables into vars.
+func (s *Step) Check(response *internal.Response, vars map[string]string) error {
+	if !internal.MatchStatus(s.Expect, response.StatusCode) {
+		return fmt.Errorf("unexpected status %d, expected %s", response.StatusCode, s.Expect)
+	}
+
+	var doc interface{}
+	decoded := json.Unmarshal(response.Body, &doc) == nil
+
+	for name, expression := range s.Extract {
+		if strings.HasPrefix(expression, "header:") {
+			value := response.Header.Get(strings.TrimSpace(strings.TrimPrefix(expression, "header:")))
+			if value == "" {
+				return fmt.Errorf("extract %s: header not found", name)
+			}
+			vars[name] = value
+			continue
+		}
+		if strings.HasPrefix(expression, "cookie:") {
+			value, ok := cookie(response.Header, strings.TrimSpace(strings.TrimPrefix(expression, "cookie:")))
+			if !ok {
+				return fmt.Errorf("extract %s: cookie not found", name)
+			}
+			vars[name] = value
+			continue
+		}
+		if !decoded {
+			return fmt.Errorf("extract %s: response body is not JSON", name)
+		}
+		value, ok := s.paths[expression].Get(doc)
+		if !ok {
+			return fmt.Errorf("extract %s: %s not found", name, expression)
+		}
+		vars[name] = String(value)
+	}
+
+	for expression, expected := range s.Assert {
+		if !decoded {
+			return fmt.Errorf("assert %s: response body is not JSON", expression)
+		}
+		value, ok := s.paths[expression].Get(doc)
+		expected = internal.Expand(expected, vars)
+		if !ok {
+			return fmt.Errorf("assert %s: not found", expression)
+		}
+		if actual := String(value); actual != expected {
+			return fmt.Errorf("assert %s: %q != %q", expression, actual, expected)
+		}
+	}
+	return nil
+}
+
+func cookie(header http.Header, name string) (string, bool) {
+	for _, c := range (&http.Response{Header: header}).Cookies() {
+		if c.Name == name {
+			return c.Value, true
+		}
+	}
+	return "", false
+}
+
+// String formats an extracted JSON value for use in a template.
+func String(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
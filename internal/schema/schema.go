@@ -0,0 +1,388 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/DustyRat/post-it/internal/file/data"
+)
+
+// Violation ...
+type Violation struct {
+	Path    string // JSON pointer into the validated document, e.g. "#/items/0/id"
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Path, v.Message)
+}
+
+// Schema is a JSON Schema node together with the document it was loaded from,
+// which is used to resolve "$ref" pointers.
+type Schema struct {
+	node     interface{}
+	document *document
+	registry *registry
+}
+
+type document struct {
+	file string
+	root interface{}
+}
+
+// registry caches every local document referenced while validating.
+type registry struct {
+	mutex     *sync.Mutex
+	documents map[string]*document
+}
+
+// Load reads a schema from a local file. References to other documents are
+// resolved relative to the file and must also be local files.
+func Load(file string) (*Schema, error) {
+	r := &registry{mutex: &sync.Mutex{}, documents: make(map[string]*document)}
+	doc, err := r.load(file)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{node: doc.root, document: doc, registry: r}, nil
+}
+
+// New returns a schema for node, a decoded JSON value found within root.
+// file is used to resolve relative references to other documents and may be empty.
+func New(file string, root, node interface{}) *Schema {
+	r := &registry{mutex: &sync.Mutex{}, documents: make(map[string]*document)}
+	doc := &document{file: file, root: root}
+	if file != "" {
+		if abs, err := filepath.Abs(file); err == nil {
+			doc.file = abs
+			r.documents[abs] = doc
+		}
+	}
+	return &Schema{node: node, document: doc, registry: r}
+}
+
+// At returns the schema for another node of the same document, sharing its
+// cache of referenced documents.
+func (s *Schema) At(node interface{}) *Schema {
+	return &Schema{node: node, document: s.document, registry: s.registry}
+}
+
+func (r *registry) load(file string) (*document, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if doc, ok := r.documents[abs]; ok {
+		return doc, nil
+	}
+
+	var root interface{}
+	if err := data.ReadFile(abs, &root); err != nil {
+		return nil, err
+	}
+	doc := &document{file: abs, root: root}
+	r.documents[abs] = doc
+	return doc, nil
+}
+
+// Validate decodes body as JSON and validates it against the schema. An
+// empty body has nothing to validate.
+func (s *Schema) Validate(body []byte) []Violation {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []Violation{{Path: "#", Message: "expected JSON, got a non-JSON body"}}
+	}
+	return s.ValidateValue(value)
+}
+
+// ValidateValue validates an already decoded JSON value.
+func (s *Schema) ValidateValue(value interface{}) []Violation {
+	return s.validate(s.node, s.document, value, "#", 0)
+}
+
+// maxDepth guards against reference cycles that never consume any input.
+const maxDepth = 256
+
+func (s *Schema) validate(node interface{}, doc *document, value interface{}, path string, depth int) []Violation {
+	if depth > maxDepth {
+		return []Violation{{Path: path, Message: "schema nesting too deep"}}
+	}
+
+	switch n := node.(type) {
+	case bool:
+		if !n {
+			return []Violation{{Path: path, Message: "no value is allowed here"}}
+		}
+		return nil
+	case map[string]interface{}:
+		return s.validateObject(n, doc, value, path, depth)
+	default:
+		return nil
+	}
+}
+
+func (s *Schema) validateObject(node map[string]interface{}, doc *document, value interface{}, path string, depth int) []Violation {
+	violations := make([]Violation, 0)
+	add := func(format string, a ...interface{}) {
+		violations = append(violations, Violation{Path: path, Message: fmt.Sprintf(format, a...)})
+	}
+
+	if ref, ok := node["$ref"].(string); ok {
+		target, targetDoc, err := s.resolve(doc, ref)
+		if err != nil {
+			add("%s", err)
+		} else {
+			violations = append(violations, s.validate(target, targetDoc, value, path, depth+1)...)
+		}
+	}
+
+	if value == nil {
+		if nullable, _ := node["nullable"].(bool); nullable {
+			return violations
+		}
+	}
+
+	if t, ok := node["type"]; ok && !matchesType(t, value) {
+		add("expected %s, got %s", typeString(t), typeOf(value))
+		// The remaining keywords are type specific and would only add noise.
+		return violations
+	}
+
+	if enum, ok := node["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if equal(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			add("value %s is not one of %s", compact(value), compact(enum))
+		}
+	}
+	if c, ok := node["const"]; ok && !equal(c, value) {
+		add("value %s does not equal %s", compact(value), compact(c))
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := len([]rune(v))
+		if min, ok := number(node["minLength"]); ok && float64(length) < min {
+			add("length %d is shorter than %v", length, min)
+		}
+		if max, ok := number(node["maxLength"]); ok && float64(length) > max {
+			add("length %d is longer than %v", length, max)
+		}
+		if pattern, ok := node["pattern"].(string); ok {
+			re, err := compilePattern(pattern)
+			if err != nil {
+				add("invalid pattern %q: %s", pattern, err)
+			} else if !re.MatchString(v) {
+				add("%q does not match pattern %q", v, pattern)
+			}
+		}
+	case float64:
+		if min, ok := number(node["minimum"]); ok {
+			if exclusive, _ := node["exclusiveMinimum"].(bool); exclusive && v <= min {
+				add("%v is not greater than %v", v, min)
+			} else if v < min {
+				add("%v is less than the minimum of %v", v, min)
+			}
+		}
+		if max, ok := number(node["maximum"]); ok {
+			if exclusive, _ := node["exclusiveMaximum"].(bool); exclusive && v >= max {
+				add("%v is not less than %v", v, max)
+			} else if v > max {
+				add("%v is greater than the maximum of %v", v, max)
+			}
+		}
+		if min, ok := number(node["exclusiveMinimum"]); ok && v <= min {
+			add("%v is not greater than %v", v, min)
+		}
+		if max, ok := number(node["exclusiveMaximum"]); ok && v >= max {
+			add("%v is not less than %v", v, max)
+		}
+		if multiple, ok := number(node["multipleOf"]); ok && multiple > 0 {
+			if q := v / multiple; q != float64(int64(q)) {
+				add("%v is not a multiple of %v", v, multiple)
+			}
+		}
+	case []interface{}:
+		if min, ok := number(node["minItems"]); ok && float64(len(v)) < min {
+			add("array has %d items, fewer than %v", len(v), min)
+		}
+		if max, ok := number(node["maxItems"]); ok && float64(len(v)) > max {
+			add("array has %d items, more than %v", len(v), max)
+		}
+		if unique, _ := node["uniqueItems"].(bool); unique {
+			for i := range v {
+				for j := i + 1; j < len(v); j++ {
+					if equal(v[i], v[j]) {
+						add("items %d and %d are equal", i, j)
+					}
+				}
+			}
+		}
+
+		// Draft 2020-12 uses prefixItems for tuples, draft-07 an array under items.
+		prefix, _ := node["prefixItems"].([]interface{})
+		items := node["items"]
+		if tuple, ok := items.([]interface{}); ok {
+			prefix = tuple
+			items = node["additionalItems"]
+		}
+		for i, item := range v {
+			itemPath := path + "/" + strconv.Itoa(i)
+			if i < len(prefix) {
+				violations = append(violations, s.validate(prefix[i], doc, item, itemPath, depth+1)...)
+			} else if items != nil {
+				violations = append(violations, s.validate(items, doc, item, itemPath, depth+1)...)
+			}
+		}
+	case map[string]interface{}:
+		if required, ok := node["required"].([]interface{}); ok {
+			for _, r := range required {
+				if name, ok := r.(string); ok {
+					if _, ok := v[name]; !ok {
+						add("missing required property %q", name)
+					}
+				}
+			}
+		}
+		if min, ok := number(node["minProperties"]); ok && float64(len(v)) < min {
+			add("object has %d properties, fewer than %v", len(v), min)
+		}
+		if max, ok := number(node["maxProperties"]); ok && float64(len(v)) > max {
+			add("object has %d properties, more than %v", len(v), max)
+		}
+
+		properties, _ := node["properties"].(map[string]interface{})
+		additional, hasAdditional := node["additionalProperties"]
+		for _, name := range sortedKeys(v) {
+			propertyPath := path + "/" + escape(name)
+			if property, ok := properties[name]; ok {
+				violations = append(violations, s.validate(property, doc, v[name], propertyPath, depth+1)...)
+			} else if hasAdditional {
+				if allowed, ok := additional.(bool); ok && !allowed {
+					violations = append(violations, Violation{Path: propertyPath, Message: "additional property is not allowed"})
+				} else {
+					violations = append(violations, s.validate(additional, doc, v[name], propertyPath, depth+1)...)
+				}
+			}
+		}
+	}
+
+	if all, ok := node["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			violations = append(violations, s.validate(sub, doc, value, path, depth+1)...)
+		}
+	}
+	if anyOf, ok := node["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if len(s.validate(sub, doc, value, path, depth+1)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			add("value does not match any schema in anyOf")
+		}
+	}
+	if one, ok := node["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, sub := range one {
+			if len(s.validate(sub, doc, value, path, depth+1)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			add("value matches %d schemas in oneOf, expected exactly 1", matches)
+		}
+	}
+	if not, ok := node["not"]; ok {
+		if len(s.validate(not, doc, value, path, depth+1)) == 0 {
+			add("value must not match the schema in not")
+		}
+	}
+	return violations
+}
+
+// resolve follows a "$ref" from doc. Only local files are supported, remote
+// references are rejected rather than fetched.
+func (s *Schema) resolve(doc *document, ref string) (interface{}, *document, error) {
+	uri, err := url.Parse(ref)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid $ref %q: %w", ref, err)
+	}
+	if uri.Scheme != "" && uri.Scheme != "file" {
+		return nil, nil, fmt.Errorf("unsupported $ref %q: only local files can be referenced", ref)
+	}
+
+	target := doc
+	if uri.Path != "" {
+		file := filepath.FromSlash(uri.Path)
+		if !filepath.IsAbs(file) && doc.file != "" {
+			file = filepath.Join(filepath.Dir(doc.file), file)
+		}
+		target, err = s.registry.load(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unresolvable $ref %q: %w", ref, err)
+		}
+	}
+
+	node, err := Pointer(target.root, uri.Fragment)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unresolvable $ref %q: %w", ref, err)
+	}
+	return node, target, nil
+}
+
+// Pointer evaluates a JSON pointer such as "/components/schemas/Pet" against root.
+func Pointer(root interface{}, pointer string) (interface{}, error) {
+	if pointer == "" {
+		return root, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+
+	node := root
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+		switch n := node.(type) {
+		case map[string]interface{}:
+			next, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("%q not found", token)
+			}
+			node = next
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil, fmt.Errorf("index %q out of range", token)
+			}
+			node = n[i]
+		default:
+			return nil, errors.New("pointer traverses a scalar value")
+		}
+	}
+	return node, nil
+}
+
+func escape(token string) string {
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
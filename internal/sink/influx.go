@@ -0,0 +1,121 @@
+package sink
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	internal "github.com/DustyRat/post-it/internal/http"
+)
+
+// measurement is the InfluxDB measurement of the results.
+const measurement = "post_it_request"
+
+// Influx writes the result of every request as an InfluxDB line protocol
+// point, to a file or to the write endpoint of a server.
+type Influx struct {
+	conf    Config
+	closer  io.Closer
+	batcher *batcher
+}
+
+// NewInflux writes to target, an http(s) URL such as
+// http://localhost:8086/write?db=loadtest, or the path of a file.
+func NewInflux(target string, conf Config) (*Influx, error) {
+	i := &Influx{conf: conf}
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		client := &http.Client{Timeout: 10 * time.Second}
+		i.batcher = newBatcher(conf, 0, func(batch []byte) error {
+			response, err := client.Post(target, "text/plain; charset=utf-8", bytes.NewReader(append(batch, '\n')))
+			if err != nil {
+				return err
+			}
+			defer response.Body.Close()
+			body, _ := ioutil.ReadAll(response.Body)
+			if response.StatusCode/100 != 2 {
+				return fmt.Errorf("influx: %s: %s", response.Status, strings.TrimSpace(string(body)))
+			}
+			return nil
+		})
+		return i, nil
+	}
+
+	file, err := os.Create(target)
+	if err != nil {
+		return nil, err
+	}
+	i.closer = file
+	i.batcher = newBatcher(conf, 0, func(batch []byte) error {
+		_, err := file.Write(append(batch, '\n'))
+		return err
+	})
+	return i, nil
+}
+
+// Sending implements internal.Recorder.
+func (i *Influx) Sending() {}
+
+// Record implements internal.Recorder.
+func (i *Influx) Record(result internal.Result) {
+	if !i.conf.sampled() {
+		return
+	}
+
+	var line strings.Builder
+	line.WriteString(measurement)
+	line.WriteString(",method=" + escape(strings.ToLower(result.Method)))
+	line.WriteString(",status=" + strconv.Itoa(result.StatusCode))
+	if result.Template != "" {
+		line.WriteString(",template=" + escape(result.Template))
+	}
+	if result.Class != "" {
+		line.WriteString(",error_class=" + result.Class)
+	}
+
+	timings := result.Timings
+	fields := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"duration_ms", result.Duration},
+		{"dns_ms", timings.DNS},
+		{"connect_ms", timings.Connect},
+		{"tls_ms", timings.TLS},
+		{"ttfb_ms", timings.TTFB},
+		{"transfer_ms", timings.Transfer},
+	}
+	for n, field := range fields {
+		if n == 0 {
+			line.WriteByte(' ')
+		} else {
+			line.WriteByte(',')
+		}
+		line.WriteString(field.name + "=" + strconv.FormatFloat(field.value.Seconds()*1000, 'f', 3, 64))
+	}
+	line.WriteString(",reused=" + strconv.FormatBool(timings.Reused))
+	line.WriteString(" " + strconv.FormatInt(result.End.UnixNano(), 10))
+	i.batcher.add([]byte(line.String()))
+}
+
+// Close implements Sink.
+func (i *Influx) Close() error {
+	err := i.batcher.close()
+	if i.closer != nil {
+		if e := i.closer.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// escape escapes the characters the line protocol uses as separators in
+// tag values.
+func escape(value string) string {
+	return strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `).Replace(value)
+}
@@ -0,0 +1,143 @@
+// Package sink pushes the result of every request to a metrics backend as
+// it's recorded, for those that don't scrape Prometheus.
+package sink
+
+import (
+	"bytes"
+	"math/rand"
+	"sync"
+	"time"
+
+	internal "github.com/DustyRat/post-it/internal/http"
+)
+
+// Sink records the results of requests, see internal.Client.SetRecorder.
+// Close flushes the results not written yet.
+type Sink interface {
+	internal.Recorder
+	Close() error
+}
+
+// Config ...
+type Config struct {
+	// SampleRate is the fraction of the results sent, from 0 to 1. Zero
+	// sends every result.
+	SampleRate float64
+
+	// BatchSize is the number of lines written at once. Zero writes every
+	// line by itself.
+	BatchSize int
+
+	// FlushInterval writes a partial batch after a while. Zero writes it
+	// only on Close.
+	FlushInterval time.Duration
+}
+
+// sampled reports whether a result is sent.
+func (c Config) sampled() bool {
+	return c.SampleRate <= 0 || c.SampleRate >= 1 || rand.Float64() < c.SampleRate
+}
+
+// batcher buffers lines and writes them in batches of at most size lines
+// and, when max is set, max bytes. Batches are written outside of the lock
+// taken to add lines, so a slow backend doesn't hold up the requests.
+type batcher struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+	lines int
+	size  int
+	max   int
+
+	writing sync.Mutex
+	write   func([]byte) error
+	err     error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newBatcher(conf Config, max int, write func([]byte) error) *batcher {
+	b := &batcher{size: conf.BatchSize, max: max, write: write, stop: make(chan struct{}), done: make(chan struct{})}
+	if b.size < 1 {
+		b.size = 1
+	}
+	if conf.FlushInterval <= 0 {
+		close(b.done)
+		return b
+	}
+	go func() {
+		defer close(b.done)
+		ticker := time.NewTicker(conf.FlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				b.flush()
+			case <-b.stop:
+				return
+			}
+		}
+	}()
+	return b
+}
+
+// add appends a line without its trailing newline.
+func (b *batcher) add(line []byte) {
+	var batches [][]byte
+	b.mutex.Lock()
+	if b.max > 0 && b.lines > 0 && b.buf.Len()+1+len(line) > b.max {
+		batches = append(batches, b.take())
+	}
+	if b.lines > 0 {
+		b.buf.WriteByte('\n')
+	}
+	b.buf.Write(line)
+	b.lines++
+	if b.lines >= b.size {
+		batches = append(batches, b.take())
+	}
+	b.mutex.Unlock()
+
+	for _, batch := range batches {
+		b.send(batch)
+	}
+}
+
+// take returns the buffered lines and empties the buffer, the mutex must be
+// held.
+func (b *batcher) take() []byte {
+	batch := append([]byte(nil), b.buf.Bytes()...)
+	b.buf.Reset()
+	b.lines = 0
+	return batch
+}
+
+func (b *batcher) flush() {
+	b.mutex.Lock()
+	var batch []byte
+	if b.lines > 0 {
+		batch = b.take()
+	}
+	b.mutex.Unlock()
+	if batch != nil {
+		b.send(batch)
+	}
+}
+
+// send writes a batch, keeping the first error for close.
+func (b *batcher) send(batch []byte) {
+	b.writing.Lock()
+	defer b.writing.Unlock()
+	if err := b.write(batch); err != nil && b.err == nil {
+		b.err = err
+	}
+}
+
+func (b *batcher) close() error {
+	close(b.stop)
+	<-b.done
+	b.flush()
+	b.writing.Lock()
+	defer b.writing.Unlock()
+	return b.err
+}
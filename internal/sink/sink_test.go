@@ -0,0 +1,140 @@
+package sink
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	internal "github.com/DustyRat/post-it/internal/http"
+)
+
+func result(template string) internal.Result {
+	return internal.Result{Method: "GET", Template: template, StatusCode: 200, Duration: 12 * time.Millisecond, End: time.Unix(1600000000, 0)}
+}
+
+func TestStatsDSplitsBatchesAtMaxPacket(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	s, err := NewStatsD(conn.LocalAddr().String(), "post_it", true, Config{BatchSize: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const results = 50
+	for i := 0; i < results; i++ {
+		s.Record(result("/items/{id}"))
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	packets, lines := 0, 0
+	buf := make([]byte, 65536)
+	for lines < 2*results {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("got %d of %d lines: %s", lines, 2*results, err)
+		}
+		if n > maxPacket {
+			t.Errorf("packet of %d bytes, more than %d", n, maxPacket)
+		}
+		packets++
+		lines += strings.Count(string(buf[:n]), "\n") + 1
+	}
+	if packets < 2 {
+		t.Errorf("got %d packet, expected the batch to be split", packets)
+	}
+}
+
+func TestBatcherFlushInterval(t *testing.T) {
+	written := make(chan string, 1)
+	b := newBatcher(Config{BatchSize: 100, FlushInterval: 10 * time.Millisecond}, 0, func(batch []byte) error {
+		written <- string(batch)
+		return nil
+	})
+	b.add([]byte("a"))
+	b.add([]byte("b"))
+
+	select {
+	case batch := <-written:
+		if batch != "a\nb" {
+			t.Errorf("got batch %q, expected %q", batch, "a\nb")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the partial batch wasn't flushed")
+	}
+	if err := b.close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatcherCloseReturnsFirstError(t *testing.T) {
+	var mutex sync.Mutex
+	errs := []error{errors.New("first"), errors.New("second"), nil}
+	b := newBatcher(Config{BatchSize: 1}, 0, func([]byte) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		err := errs[0]
+		errs = errs[1:]
+		return err
+	})
+	b.add([]byte("a"))
+	b.add([]byte("b"))
+	b.add([]byte("c"))
+	if err := b.close(); err == nil || err.Error() != "first" {
+		t.Errorf("got error %v, expected first", err)
+	}
+}
+
+func TestInfluxEscapesTags(t *testing.T) {
+	var mutex sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mutex.Lock()
+		bodies = append(bodies, string(body))
+		mutex.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	i, err := NewInflux(server.URL+"/write?db=test", Config{BatchSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.Record(result("/a b,c=d"))
+	if err := i.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `post_it_request,method=get,status=200,template=/a\ b\,c\=d duration_ms=12.000,`
+	if len(bodies) != 1 || !strings.HasPrefix(bodies[0], expected) || !strings.HasSuffix(bodies[0], " 1600000000000000000\n") {
+		t.Errorf("got %q, expected a line starting with %q", bodies, expected)
+	}
+}
+
+func TestInfluxReportsServerErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unable to parse", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	i, err := NewInflux(server.URL+"/write?db=test", Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.Record(result("/"))
+	if err := i.Close(); err == nil || !strings.Contains(err.Error(), "400 Bad Request: unable to parse") {
+		t.Errorf("got error %v, expected the status and body of the response", err)
+	}
+}
@@ -0,0 +1,102 @@
+package sink
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	internal "github.com/DustyRat/post-it/internal/http"
+)
+
+// maxPacket keeps the batches of StatsD lines within a single UDP packet on
+// an Ethernet network.
+const maxPacket = 1432
+
+// StatsD sends the timing and status of every request to a StatsD server
+// over UDP, with DogStatsD tags when tags is set.
+type StatsD struct {
+	conf    Config
+	conn    net.Conn
+	prefix  string
+	tags    bool
+	batcher *batcher
+}
+
+// NewStatsD connects to the StatsD server at addr, e.g. localhost:8125.
+// Metric names start with prefix, e.g. post_it.
+func NewStatsD(addr, prefix string, tags bool, conf Config) (*StatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, ".") {
+		prefix += "."
+	}
+	s := &StatsD{conf: conf, conn: conn, prefix: prefix, tags: tags}
+	s.batcher = newBatcher(conf, maxPacket, func(batch []byte) error {
+		_, err := conn.Write(batch)
+		return err
+	})
+	return s, nil
+}
+
+// Sending implements internal.Recorder.
+func (s *StatsD) Sending() {}
+
+// Record implements internal.Recorder.
+func (s *StatsD) Record(result internal.Result) {
+	if !s.conf.sampled() {
+		return
+	}
+	rate := ""
+	if s.conf.SampleRate > 0 && s.conf.SampleRate < 1 {
+		rate = "|@" + strconv.FormatFloat(s.conf.SampleRate, 'f', -1, 64)
+	}
+
+	method := strings.ToLower(result.Method)
+	var tags, status string
+	if result.Class != "" {
+		status = "error"
+	} else {
+		status = strconv.Itoa(result.StatusCode)
+	}
+	if s.tags {
+		tags = fmt.Sprintf("|#method:%s,status:%s,template:%s", method, status, tag(result.Template))
+		if result.Class != "" {
+			tags += ",error_class:" + result.Class
+		}
+		s.line("request.duration", duration(result), "ms", rate, tags)
+		s.line("requests", "1", "c", rate, tags)
+		return
+	}
+	// Plain StatsD has no tags, the method and status are part of the name.
+	s.line("request.duration."+method, duration(result), "ms", rate, "")
+	if result.Class != "" {
+		s.line("requests."+method+".error."+result.Class, "1", "c", rate, "")
+		return
+	}
+	s.line("requests."+method+"."+status, "1", "c", rate, "")
+}
+
+func (s *StatsD) line(name, value, kind, rate, tags string) {
+	s.batcher.add([]byte(s.prefix + name + ":" + value + "|" + kind + rate + tags))
+}
+
+// Close implements Sink.
+func (s *StatsD) Close() error {
+	err := s.batcher.close()
+	if e := s.conn.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+func duration(result internal.Result) string {
+	return strconv.FormatFloat(result.Duration.Seconds()*1000, 'f', 3, 64)
+}
+
+// tag replaces the characters DogStatsD uses as separators.
+func tag(value string) string {
+	return strings.NewReplacer(",", "_", "|", "_", "#", "_").Replace(value)
+}
@@ -0,0 +1,163 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/DustyRat/post-it/internal/diff"
+	"github.com/DustyRat/post-it/internal/jsonpath"
+)
+
+// Mode ...
+type Mode string
+
+// Modes of a snapshot store.
+const (
+	Record Mode = "record"
+	Verify Mode = "verify"
+)
+
+// Result ...
+type Result string
+
+// Results of snapshotting a single response.
+const (
+	Recorded Result = "recorded"
+	Matched  Result = "matched"
+	Changed  Result = "changed"
+	Updated  Result = "updated"
+	Missing  Result = "missing"
+)
+
+// Snapshot is the normalized form of a response that is stored on disk.
+type Snapshot struct {
+	Status int         `json:"status"`
+	Body   interface{} `json:"body"`
+}
+
+// Store keeps one snapshot file per row in a directory, named after the row key.
+type Store struct {
+	Dir    string
+	Mode   Mode
+	Update bool
+	Ignore []*jsonpath.Path
+
+	mutex   sync.Mutex
+	written map[string]bool
+}
+
+// New ...
+func New(dir string, mode Mode, update bool, ignore []*jsonpath.Path) (*Store, error) {
+	switch mode {
+	case Record:
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return nil, err
+		}
+	case Verify:
+		if info, err := os.Stat(dir); err != nil {
+			return nil, err
+		} else if !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", dir)
+		}
+	default:
+		return nil, fmt.Errorf("unknown snapshot mode %q, expected record or verify", mode)
+	}
+	return &Store{Dir: dir, Mode: mode, Update: update, Ignore: ignore, written: make(map[string]bool)}, nil
+}
+
+// Normalize builds a snapshot from a response. JSON bodies are decoded and
+// stripped of ignored fields, other bodies are kept as a string.
+func (s *Store) Normalize(status int, body []byte) Snapshot {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return Snapshot{Status: status, Body: string(body)}
+	}
+	return Snapshot{Status: status, Body: diff.Strip(doc, s.Ignore)}
+}
+
+// Check records or verifies the snapshot for a row, depending on the mode of
+// the store. In verify mode the changes from the stored snapshot are returned.
+func (s *Store) Check(key string, snapshot Snapshot) (Result, []diff.Change, error) {
+	if key == "" {
+		return "", nil, errors.New("empty snapshot key")
+	}
+
+	if s.Mode == Record {
+		return Recorded, nil, s.write(key, snapshot)
+	}
+
+	stored, err := s.read(key)
+	if os.IsNotExist(err) {
+		if s.Update {
+			return Updated, nil, s.write(key, snapshot)
+		}
+		return Missing, nil, nil
+	} else if err != nil {
+		return "", nil, err
+	}
+
+	changes := make([]diff.Change, 0)
+	if stored.Status != snapshot.Status {
+		changes = append(changes, diff.Change{Path: "status", Kind: diff.Changed, A: stored.Status, B: snapshot.Status})
+	}
+	changes = append(changes, diff.JSON(stored.Body, snapshot.Body, s.Ignore)...)
+	if len(changes) == 0 {
+		return Matched, nil, nil
+	}
+	if s.Update {
+		return Updated, changes, s.write(key, snapshot)
+	}
+	return Changed, changes, nil
+}
+
+// file returns the path of the snapshot of key. Bytes other than letters,
+// digits, '.', '_' and '-' are escaped as %XX so that every key has a file
+// of its own.
+func (s *Store) file(key string) string {
+	var name strings.Builder
+	for i := 0; i < len(key); i++ {
+		b := key[i]
+		if 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' || b == '.' || b == '_' || b == '-' {
+			name.WriteByte(b)
+		} else {
+			fmt.Fprintf(&name, "%%%02X", b)
+		}
+	}
+	return filepath.Join(s.Dir, name.String()+".json")
+}
+
+func (s *Store) read(key string) (*Snapshot, error) {
+	b, err := ioutil.ReadFile(s.file(key))
+	if err != nil {
+		return nil, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(b, &snapshot); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.file(key), err)
+	}
+	return &snapshot, nil
+}
+
+// write writes the snapshot of key, failing when another row of the run
+// already wrote it.
+func (s *Store) write(key string, snapshot Snapshot) error {
+	s.mutex.Lock()
+	duplicate := s.written[key]
+	s.written[key] = true
+	s.mutex.Unlock()
+	if duplicate {
+		return fmt.Errorf("duplicate snapshot key %q", key)
+	}
+
+	b, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.file(key), append(b, '\n'), 0666)
+}
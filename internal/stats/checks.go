@@ -0,0 +1,135 @@
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/DustyRat/post-it/internal/hdr"
+)
+
+// Kinds of the checks counted by a collector, see Checks.
+const (
+	// Schema counts the response bodies validated against the JSON Schema
+	// by result: valid and invalid, and the number of violations found.
+	Schema = "schema"
+	// Compare counts the comparisons by result: match, mismatch and error.
+	Compare = "compare"
+	// Snapshot counts the snapshots by result: recorded, matched, changed,
+	// missing, updated and error.
+	Snapshot = "snapshot"
+	// SnapshotChange counts the fields that differ from their snapshot by
+	// kind: added, removed and changed.
+	SnapshotChange = "snapshot_change"
+	// Refresh counts the credential refreshes by result: ok and failed.
+	Refresh = "refresh"
+)
+
+// Step is what happened to a step of a scenario.
+type Step struct {
+	Index int
+	Name  string
+	// Results are the number of rows by result: ok, failed and skipped.
+	Results map[string]uint64
+	Latency *hdr.Histogram
+}
+
+// Validated records the validation of a response body against the JSON
+// Schema and the number of violations found.
+func (c *Collector) Validated(violations int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if violations == 0 {
+		c.check(Schema)["valid"]++
+		return
+	}
+	c.check(Schema)["invalid"]++
+	c.check(Schema)["violations"] += uint64(violations)
+}
+
+// Compared records the result of a comparison: match, mismatch or error.
+func (c *Collector) Compared(result string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.check(Compare)[result]++
+}
+
+// Snapshotted records the result of a snapshot and the kinds of its
+// changes.
+func (c *Collector) Snapshotted(result string, changes []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.check(Snapshot)[result]++
+	for _, kind := range changes {
+		c.check(SnapshotChange)[kind]++
+	}
+}
+
+// Refreshed implements internal.RefreshRecorder.
+func (c *Collector) Refreshed(result string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.check(Refresh)[result]++
+}
+
+// Stepped records the result of a step of a scenario for a row: ok, failed
+// or skipped.
+func (c *Collector) Stepped(index int, name, result string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.step(index, name).Results[result]++
+}
+
+// StepLatency records the latency of the response of a step of a scenario.
+func (c *Collector) StepLatency(index int, name string, d time.Duration) {
+	c.mutex.Lock()
+	step := c.step(index, name)
+	c.mutex.Unlock()
+	step.Latency.Record(d)
+}
+
+// check returns the counts of a kind of check, the mutex must be held.
+func (c *Collector) check(kind string) map[string]uint64 {
+	counts, ok := c.checks[kind]
+	if !ok {
+		counts = make(map[string]uint64)
+		c.checks[kind] = counts
+	}
+	return counts
+}
+
+// step returns the step of index, the mutex must be held.
+func (c *Collector) step(index int, name string) *Step {
+	step, ok := c.steps[index]
+	if !ok {
+		step = &Step{Index: index, Name: name, Results: make(map[string]uint64), Latency: hdr.New()}
+		c.steps[index] = step
+	}
+	return step
+}
+
+// Checks returns the counts of a kind of check, e.g. Schema, by result.
+func (c *Collector) Checks(kind string) map[string]uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	out := make(map[string]uint64, len(c.checks[kind]))
+	for result, count := range c.checks[kind] {
+		out[result] = count
+	}
+	return out
+}
+
+// Steps returns the steps of the scenario recorded so far, in order.
+func (c *Collector) Steps() []*Step {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	out := make([]*Step, 0, len(c.steps))
+	for _, step := range c.steps {
+		results := make(map[string]uint64, len(step.Results))
+		for result, count := range step.Results {
+			results[result] = count
+		}
+		out = append(out, &Step{Index: step.Index, Name: step.Name, Results: results, Latency: step.Latency})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Index < out[j].Index })
+	return out
+}
@@ -0,0 +1,275 @@
+package stats
+
+import (
+	"container/heap"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DustyRat/post-it/internal/hdr"
+	internal "github.com/DustyRat/post-it/internal/http"
+)
+
+// Phases of a request, in the order they happen.
+var Phases = []string{"dns", "connect", "tls", "ttfb", "transfer"}
+
+// Key identifies the requests of a series.
+type Key struct {
+	Method   string
+	Template string
+	// Status is the class of the status code, e.g. 2xx, or none when the
+	// request failed.
+	Status string
+	// Class is the class of the error of a failed request.
+	Class string
+}
+
+// Series are the results of the requests of a key.
+type Series struct {
+	Key     Key
+	Latency *hdr.Histogram
+}
+
+// Second is what happened in a second of the run.
+type Second struct {
+	Requests uint64
+	Errors   uint64
+	// Sum and Max are of the latencies of the requests that got a response.
+	Sum time.Duration
+	Max time.Duration
+}
+
+// Collector records the results of the requests of a run. It's safe for
+// concurrent use.
+type Collector struct {
+	mutex    sync.Mutex
+	series   map[Key]*Series
+	statuses map[int]uint64
+	errors   map[string]uint64
+	reused   map[bool]uint64
+	inflight int64
+	start    int64
+	seconds  []Second
+	slowest  slowest
+	keep     int
+	checks   map[string]map[string]uint64
+	steps    map[int]*Step
+
+	// Latency and Throughput are of the requests that got a response.
+	Latency    *hdr.Histogram
+	Throughput *hdr.Rate
+	Phases     map[string]*hdr.Histogram
+	// Comparison are the latencies of the compared responses of each
+	// target: a and b.
+	Comparison map[string]*hdr.Histogram
+}
+
+// New ...
+func New() *Collector {
+	c := &Collector{
+		series:     make(map[Key]*Series),
+		statuses:   make(map[int]uint64),
+		errors:     make(map[string]uint64),
+		reused:     make(map[bool]uint64),
+		checks:     make(map[string]map[string]uint64),
+		steps:      make(map[int]*Step),
+		Latency:    hdr.New(),
+		Throughput: hdr.NewRate(),
+		Phases:     make(map[string]*hdr.Histogram),
+		Comparison: map[string]*hdr.Histogram{"a": hdr.New(), "b": hdr.New()},
+	}
+	for _, phase := range Phases {
+		c.Phases[phase] = hdr.New()
+	}
+	return c
+}
+
+// Sending implements internal.Recorder.
+func (c *Collector) Sending() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.inflight++
+}
+
+// Record implements internal.Recorder.
+func (c *Collector) Record(result internal.Result) {
+	key := Key{Method: strings.ToLower(result.Method), Template: result.Template, Status: StatusClass(result.StatusCode), Class: result.Class}
+
+	c.mutex.Lock()
+	c.inflight--
+	series, ok := c.series[key]
+	if !ok {
+		series = &Series{Key: key, Latency: hdr.New()}
+		c.series[key] = series
+	}
+	second := c.second(result.End)
+	second.Requests++
+	if result.Class != "" {
+		c.errors[result.Class]++
+		second.Errors++
+	} else {
+		c.statuses[result.StatusCode]++
+		c.reused[result.Timings.Reused]++
+		second.Sum += result.Duration
+		if result.Duration > second.Max {
+			second.Max = result.Duration
+		}
+	}
+	if c.keep > 0 {
+		if c.slowest.Len() < c.keep {
+			heap.Push(&c.slowest, result)
+		} else if result.Duration > c.slowest[0].Duration {
+			c.slowest[0] = result
+			heap.Fix(&c.slowest, 0)
+		}
+	}
+	c.mutex.Unlock()
+
+	series.Latency.Record(result.Duration)
+	if result.Class != "" {
+		return
+	}
+	c.Latency.Record(result.Duration)
+	c.Throughput.Add(result.End)
+
+	timings := result.Timings
+	if !timings.Reused {
+		c.Phases["dns"].Record(timings.DNS)
+		c.Phases["connect"].Record(timings.Connect)
+	}
+	if timings.TLS > 0 {
+		c.Phases["tls"].Record(timings.TLS)
+	}
+	if timings.TTFB > 0 {
+		c.Phases["ttfb"].Record(timings.TTFB)
+		c.Phases["transfer"].Record(timings.Transfer)
+	}
+}
+
+// second returns the second of at, the mutex must be held.
+func (c *Collector) second(at time.Time) *Second {
+	unix := at.Unix()
+	if len(c.seconds) == 0 {
+		c.start = unix
+	}
+	if unix < c.start {
+		seconds := make([]Second, int(c.start-unix)+len(c.seconds))
+		copy(seconds[c.start-unix:], c.seconds)
+		c.seconds, c.start = seconds, unix
+	}
+	i := int(unix - c.start)
+	for i >= len(c.seconds) {
+		c.seconds = append(c.seconds, Second{})
+	}
+	return &c.seconds[i]
+}
+
+// Timeline returns what happened in each second, from the second of the
+// first result to the second of the last.
+func (c *Collector) Timeline() []Second {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]Second(nil), c.seconds...)
+}
+
+// KeepSlowest keeps the n slowest results, see Slowest.
+func (c *Collector) KeepSlowest(n int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.keep = n
+}
+
+// Slowest returns the slowest results kept, slowest first.
+func (c *Collector) Slowest() []internal.Result {
+	c.mutex.Lock()
+	out := append([]internal.Result(nil), c.slowest...)
+	c.mutex.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Duration > out[j].Duration })
+	return out
+}
+
+// slowest is a min-heap of results by duration, the fastest of the slowest
+// is replaced first.
+type slowest []internal.Result
+
+func (s slowest) Len() int            { return len(s) }
+func (s slowest) Less(i, j int) bool  { return s[i].Duration < s[j].Duration }
+func (s slowest) Swap(i, j int)       { s[i], s[j] = s[j], s[i] }
+func (s *slowest) Push(x interface{}) { *s = append(*s, x.(internal.Result)) }
+func (s *slowest) Pop() interface{} {
+	old := *s
+	x := old[len(old)-1]
+	*s = old[:len(old)-1]
+	return x
+}
+
+// Series returns the series recorded so far, ordered by key.
+func (c *Collector) Series() []*Series {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	out := make([]*Series, 0, len(c.series))
+	for _, series := range c.series {
+		out = append(out, series)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i].Key, out[j].Key
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		if a.Template != b.Template {
+			return a.Template < b.Template
+		}
+		if a.Status != b.Status {
+			return a.Status < b.Status
+		}
+		return a.Class < b.Class
+	})
+	return out
+}
+
+// Statuses returns the number of responses of each status code.
+func (c *Collector) Statuses() map[int]uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	out := make(map[int]uint64, len(c.statuses))
+	for code, count := range c.statuses {
+		out[code] = count
+	}
+	return out
+}
+
+// Errors returns the number of failed requests of each error class.
+func (c *Collector) Errors() map[string]uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	out := make(map[string]uint64, len(c.errors))
+	for class, count := range c.errors {
+		out[class] = count
+	}
+	return out
+}
+
+// InFlight returns the number of requests being sent.
+func (c *Collector) InFlight() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.inflight
+}
+
+// Connections returns the number of responses on new and reused
+// connections.
+func (c *Collector) Connections() (created, reused uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.reused[false], c.reused[true]
+}
+
+// StatusClass returns the class of a status code, e.g. 2xx, or none for 0.
+func StatusClass(code int) string {
+	if code == 0 {
+		return "none"
+	}
+	return strconv.Itoa(code/100) + "xx"
+}
@@ -0,0 +1,187 @@
+package stats
+
+import (
+	"strconv"
+
+	"github.com/DustyRat/post-it/internal/hdr"
+	internal "github.com/DustyRat/post-it/internal/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// quantiles of the summaries of the Prometheus view.
+var quantiles = []float64{0.5, 0.9, 0.99, 1}
+
+var (
+	requestsDesc = prometheus.NewDesc(
+		"http_outbound_requests_total",
+		"Counter of Outbound HTTP requests by method, URL template, status class and error class.",
+		[]string{"method", "template", "status_class", "error_class"}, nil,
+	)
+	durationDesc = prometheus.NewDesc(
+		"http_outbound_request_duration_seconds",
+		"Summary of latencies for Outbound HTTP requests by method, URL template, status class and error class.",
+		[]string{"method", "template", "status_class", "error_class"}, nil,
+	)
+	errorsDesc = prometheus.NewDesc(
+		"http_outbound_errors_total",
+		"Counter of failed Outbound HTTP requests by error class.",
+		[]string{"class"}, nil,
+	)
+	inflightDesc = prometheus.NewDesc(
+		"http_outbound_requests_in_flight",
+		"Number of Outbound HTTP requests being sent.",
+		nil, nil,
+	)
+	phasesDesc = prometheus.NewDesc(
+		"http_outbound_request_phase_seconds",
+		"Summary of the phases of Outbound HTTP requests: dns, connect, tls, ttfb and transfer.",
+		[]string{"phase"}, nil,
+	)
+	connectionsDesc = prometheus.NewDesc(
+		"http_outbound_connections_total",
+		"Counter of connections used by Outbound HTTP requests by whether they were reused.",
+		[]string{"reused"}, nil,
+	)
+	refreshesDesc = prometheus.NewDesc(
+		"http_outbound_credential_refreshes_total",
+		"Counter of credential refreshes by result.",
+		[]string{"result"}, nil,
+	)
+	validationsDesc = prometheus.NewDesc(
+		"response_schema_validations_total",
+		"Counter of response bodies validated against the JSON Schema by result.",
+		[]string{"result"}, nil,
+	)
+	violationsDesc = prometheus.NewDesc(
+		"response_schema_violations_total",
+		"Counter of JSON Schema violations found in response bodies.",
+		nil, nil,
+	)
+	comparisonsDesc = prometheus.NewDesc(
+		"comparisons_total",
+		"Counter of responses compared between both targets by result.",
+		[]string{"result"}, nil,
+	)
+	comparisonDurationDesc = prometheus.NewDesc(
+		"comparison_request_duration_seconds",
+		"Summary of latencies for compared requests by target.",
+		[]string{"target"}, nil,
+	)
+	snapshotsDesc = prometheus.NewDesc(
+		"snapshots_total",
+		"Counter of response snapshots by result.",
+		[]string{"result"}, nil,
+	)
+	snapshotChangesDesc = prometheus.NewDesc(
+		"snapshot_changes_total",
+		"Counter of fields that differ from their stored snapshot by kind of change.",
+		[]string{"kind"}, nil,
+	)
+	stepsDesc = prometheus.NewDesc(
+		"scenario_steps_total",
+		"Counter of scenario steps by result.",
+		[]string{"index", "step", "result"}, nil,
+	)
+	stepDurationDesc = prometheus.NewDesc(
+		"scenario_step_duration_seconds",
+		"Summary of latencies for scenario steps.",
+		[]string{"index", "step"}, nil,
+	)
+)
+
+// view exposes a collector to Prometheus.
+type view struct {
+	collector *Collector
+}
+
+// Prometheus returns a Prometheus view of the results recorded by c, for
+// exporting them while the run goes on.
+func (c *Collector) Prometheus() prometheus.Collector {
+	return &view{collector: c}
+}
+
+// Describe implements prometheus.Collector.
+func (v *view) Describe(ch chan<- *prometheus.Desc) {
+	ch <- requestsDesc
+	ch <- durationDesc
+	ch <- errorsDesc
+	ch <- inflightDesc
+	ch <- phasesDesc
+	ch <- connectionsDesc
+	ch <- refreshesDesc
+	ch <- validationsDesc
+	ch <- violationsDesc
+	ch <- comparisonsDesc
+	ch <- comparisonDurationDesc
+	ch <- snapshotsDesc
+	ch <- snapshotChangesDesc
+	ch <- stepsDesc
+	ch <- stepDurationDesc
+}
+
+// Collect implements prometheus.Collector.
+func (v *view) Collect(ch chan<- prometheus.Metric) {
+	for _, series := range v.collector.Series() {
+		key := series.Key
+		ch <- prometheus.MustNewConstMetric(requestsDesc, prometheus.CounterValue, float64(series.Latency.Count()), key.Method, key.Template, key.Status, key.Class)
+		ch <- summary(durationDesc, series.Latency, key.Method, key.Template, key.Status, key.Class)
+	}
+	errors := v.collector.Errors()
+	for _, class := range internal.ErrorClasses {
+		ch <- prometheus.MustNewConstMetric(errorsDesc, prometheus.CounterValue, float64(errors[class]), class)
+	}
+	ch <- prometheus.MustNewConstMetric(inflightDesc, prometheus.GaugeValue, float64(v.collector.InFlight()))
+	for _, phase := range Phases {
+		if h := v.collector.Phases[phase]; h.Count() > 0 {
+			ch <- summary(phasesDesc, h, phase)
+		}
+	}
+	created, reused := v.collector.Connections()
+	ch <- prometheus.MustNewConstMetric(connectionsDesc, prometheus.CounterValue, float64(created), strconv.FormatBool(false))
+	ch <- prometheus.MustNewConstMetric(connectionsDesc, prometheus.CounterValue, float64(reused), strconv.FormatBool(true))
+
+	counters(ch, refreshesDesc, v.collector.Checks(Refresh))
+	schema := v.collector.Checks(Schema)
+	if violations, ok := schema["violations"]; ok {
+		delete(schema, "violations")
+		ch <- prometheus.MustNewConstMetric(violationsDesc, prometheus.CounterValue, float64(violations))
+	}
+	counters(ch, validationsDesc, schema)
+	counters(ch, comparisonsDesc, v.collector.Checks(Compare))
+	for _, target := range []string{"a", "b"} {
+		if h := v.collector.Comparison[target]; h.Count() > 0 {
+			ch <- summary(comparisonDurationDesc, h, target)
+		}
+	}
+	counters(ch, snapshotsDesc, v.collector.Checks(Snapshot))
+	counters(ch, snapshotChangesDesc, v.collector.Checks(SnapshotChange))
+	for _, step := range v.collector.Steps() {
+		index := strconv.Itoa(step.Index)
+		for result, count := range step.Results {
+			ch <- prometheus.MustNewConstMetric(stepsDesc, prometheus.CounterValue, float64(count), index, step.Name, result)
+		}
+		if step.Latency.Count() > 0 {
+			ch <- summary(stepDurationDesc, step.Latency, index, step.Name)
+		}
+	}
+}
+
+// counters sends a counter of each result of a kind of check.
+func counters(ch chan<- prometheus.Metric, desc *prometheus.Desc, counts map[string]uint64) {
+	for label, count := range counts {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(count), label)
+	}
+}
+
+func summary(desc *prometheus.Desc, h *hdr.Histogram, labels ...string) prometheus.Metric {
+	ps := make([]float64, len(quantiles))
+	for i, q := range quantiles {
+		ps[i] = q * 100
+	}
+	values := make(map[float64]float64, len(quantiles))
+	for i, value := range h.Percentiles(ps...) {
+		values[quantiles[i]] = value.Seconds()
+	}
+	return prometheus.MustNewConstSummary(desc, h.Count(), h.Sum().Seconds(), values, labels...)
+}
@@ -0,0 +1,61 @@
+package stats
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Server serves metrics at /metrics in the Prometheus exposition format.
+type Server struct {
+	server *http.Server
+	done   chan error
+}
+
+// Serve starts serving the metrics of gatherer on addr, e.g. :9100.
+func Serve(addr string, gatherer prometheus.Gatherer) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		families, err := gatherer.Gather()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		format := expfmt.Negotiate(r.Header)
+		w.Header().Set("Content-Type", string(format))
+		encoder := expfmt.NewEncoder(w, format)
+		for _, family := range families {
+			if err := encoder.Encode(family); err != nil {
+				return
+			}
+		}
+	})
+
+	s := &Server{server: &http.Server{Handler: mux}, done: make(chan error, 1)}
+	go func() { s.done <- s.server.Serve(listener) }()
+	return s, nil
+}
+
+// Close keeps serving for grace, so scrapers see the final numbers of the
+// run, then shuts the server down.
+func (s *Server) Close(grace time.Duration) error {
+	time.Sleep(grace)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		return err
+	}
+	if err := <-s.done; err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
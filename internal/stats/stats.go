@@ -2,152 +2,214 @@ package stats
 
 import (
 	"fmt"
-	"math"
 	"net/http"
 	"os"
 	"sort"
 	"strconv"
+	"strings"
 	"text/tabwriter"
 	"time"
 
+	"github.com/DustyRat/post-it/internal/hdr"
 	internal "github.com/DustyRat/post-it/internal/http"
 	"github.com/DustyRat/post-it/internal/options"
-
-	io_prometheus_client "github.com/prometheus/client_model/go"
 )
 
+// buckets are the upper bounds of the histogram printed with --histogram.
+var buckets = []time.Duration{
+	time.Millisecond, 2500 * time.Microsecond, 5 * time.Millisecond, 7500 * time.Microsecond,
+	10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond, 75 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond, 750 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second, 7500 * time.Millisecond,
+	10 * time.Second,
+}
+
 // Print ...
-func Print(opts options.Options, elapsed time.Duration) {
+func Print(opts options.Options, collector *Collector, elapsed time.Duration) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.AlignRight|tabwriter.Debug)
 
-	var summaries, histograms, counters []*io_prometheus_client.Metric
-	metrics, _ := internal.Gatherer.Gather()
-	for _, metric := range metrics {
-		switch metric.GetType() {
-		case io_prometheus_client.MetricType_COUNTER:
-			counters = metric.GetMetric()
-		case io_prometheus_client.MetricType_HISTOGRAM:
-			histograms = metric.GetMetric()
-		case io_prometheus_client.MetricType_SUMMARY:
-			summaries = metric.GetMetric()
-		}
+	fmt.Fprintln(w, "\nResponses")
+	for _, row := range Responses(collector) {
+		fmt.Fprintln(w, strings.Join(row, " \t ")+" \t ")
 	}
-
-	histgram := make(map[time.Duration]uint64)
-	hbuckets := make(sort.IntSlice, 0)
-	var count uint64
-	var average, max time.Duration
-	var rate float64
-	for _, h := range histograms {
-		histogram := h.GetHistogram()
-		average = time.Duration(histogram.GetSampleSum()*float64(time.Second)) / time.Duration(histogram.GetSampleCount())
-		rate = float64(histogram.GetSampleCount()) / elapsed.Seconds()
-		count = histogram.GetSampleCount()
-		for _, bucket := range histogram.GetBucket() {
-			upperBound := time.Duration(bucket.GetUpperBound() * float64(time.Second))
-			count := bucket.GetCumulativeCount()
-			histgram[upperBound] = count
-			hbuckets = append(hbuckets, int(upperBound))
-		}
+	percentiles := Percentiles(opts)
+	fmt.Fprintln(w, "Statistics")
+	for _, row := range Statistics(collector, percentiles, elapsed) {
+		fmt.Fprintln(w, strings.Join(row, " \t "))
 	}
-	hbuckets.Sort()
 
-	for i := range hbuckets {
-		current := time.Duration(hbuckets[i])
-		if i < len(hbuckets)-1 {
-			next := time.Duration(hbuckets[i+1])
-			histgram[current] = histgram[next] - histgram[current]
-			continue
-		}
-		histgram[current] = count - histgram[current]
+	if opts.Refresh.File != "" || opts.Client.OAuth2.TokenURL != "" {
+		results := collector.Checks(Refresh)
+		fmt.Fprintln(w, "Refreshes")
+		fmt.Fprintln(w, "OK \t Failed")
+		fmt.Fprintln(w, fmt.Sprintf("%d \t %d", results["ok"], results["failed"]))
 	}
 
-	var cumlative float64
-	for bucket, count := range histgram {
-		cumlative += float64(count) * math.Pow(float64(bucket-average), 2)
-	}
-	stddev := time.Duration(math.Sqrt(cumlative / float64(count)))
-	if stddev < 0 {
-		stddev = 0
+	if opts.Schema != "" {
+		results := collector.Checks(Schema)
+		fmt.Fprintln(w, "Schema")
+		fmt.Fprintln(w, "Valid \t Invalid \t Violations")
+		fmt.Fprintln(w, fmt.Sprintf("%d \t %d \t %d", results["valid"], results["invalid"], results["violations"]))
 	}
 
-	quantiles := make(map[float64]time.Duration)
-	qbuckets := make(sort.Float64Slice, 0)
-	for _, s := range summaries {
-		summary := s.GetSummary()
-		for _, quantile := range summary.GetQuantile() {
-			value := time.Duration(quantile.GetValue() * float64(time.Second))
-			if quantile.GetQuantile() < 1 {
-				bucket := quantile.GetQuantile()
-				quantiles[bucket] = value
-				qbuckets = append(qbuckets, bucket)
+	if opts.Scenario != "" {
+		fmt.Fprintln(w, "Steps")
+		fmt.Fprintln(w, "Step \t OK \t Failed \t Skipped \t Average"+columns(percentiles)+" \t Max")
+		for _, s := range collector.Steps() {
+			line := fmt.Sprintf("%s \t %d \t %d \t %d", s.Name, s.Results["ok"], s.Results["failed"], s.Results["skipped"])
+			if s.Latency.Count() > 0 {
+				line += fmt.Sprintf(" \t %s", round(s.Latency.Mean(), 2))
+				for _, value := range s.Latency.Percentiles(append(append([]float64(nil), percentiles...), 100)...) {
+					line += fmt.Sprintf(" \t %s", round(value, 2))
+				}
 			} else {
-				max = value
+				line += " \t -" + strings.Repeat(" \t -", len(percentiles)+1)
 			}
+			fmt.Fprintln(w, line)
 		}
 	}
-	qbuckets.Sort()
 
-	fmt.Fprintln(w, "\nResponses")
-	statuses := make(map[int]int)
-	codes := make(sort.IntSlice, 0)
-	for _, counter := range counters {
-		var code int
-		for _, label := range counter.GetLabel() {
-			if label.GetName() == "code" {
-				code, _ = strconv.Atoi(label.GetValue())
-				codes = append(codes, code)
-				break
+	if opts.Compare.URL != "" {
+		results := collector.Checks(Compare)
+		fmt.Fprintln(w, "Comparison")
+		fmt.Fprintln(w, "Match \t Mismatch \t Errors")
+		fmt.Fprintln(w, fmt.Sprintf("%d \t %d \t %d", results["match"], results["mismatch"], results["error"]))
+
+		fmt.Fprintln(w, "Latency \t Average"+columns(percentiles)+" \t Max")
+		for _, target := range []string{"a", "b"} {
+			h := collector.Comparison[target]
+			if h.Count() == 0 {
+				continue
 			}
+			line := fmt.Sprintf("%s \t %s", strings.ToUpper(target), round(h.Mean(), 2))
+			for _, value := range h.Percentiles(append(append([]float64(nil), percentiles...), 100)...) {
+				line += fmt.Sprintf(" \t %s", round(value, 2))
+			}
+			fmt.Fprintln(w, line)
 		}
-		value := counter.Counter.GetValue()
-		statuses[code] = int(value)
 	}
-	codes.Sort()
 
-	headers, line := "", ""
-	values := make([]interface{}, 0)
-	for _, code := range codes {
-		if code != 0 {
-			headers += fmt.Sprintf("%s: %d \t ", http.StatusText(code), code)
-			line += "%d \t "
-			values = append(values, statuses[code])
+	if opts.Snapshot.Dir != "" {
+		results := collector.Checks(Snapshot)
+		kinds := collector.Checks(SnapshotChange)
+		fmt.Fprintln(w, "Snapshots")
+		if opts.Snapshot.Mode == "record" {
+			fmt.Fprintln(w, "Recorded \t Errors")
+			fmt.Fprintln(w, fmt.Sprintf("%d \t %d", results["recorded"], results["error"]))
 		} else {
-			headers += "Errors \t "
-			line += "%d \t "
-			values = append(values, statuses[code])
+			fmt.Fprintln(w, "Matched \t Changed \t Missing \t Updated \t Errors")
+			fmt.Fprintln(w, fmt.Sprintf("%d \t %d \t %d \t %d \t %d", results["matched"], results["changed"], results["missing"], results["updated"], results["error"]))
+			fmt.Fprintln(w, "Fields \t Added \t Removed \t Changed")
+			fmt.Fprintln(w, fmt.Sprintf(" \t %d \t %d \t %d", kinds["added"], kinds["removed"], kinds["changed"]))
 		}
 	}
-	fmt.Fprintln(w, headers)
-	fmt.Fprintf(w, fmt.Sprintf("%s\n", line), values...)
-
-	fmt.Fprintln(w, "Statistics")
-	fmt.Fprintln(w, " \t Average \t STDDEV \t Max")
-	fmt.Fprintln(w, fmt.Sprintf("Req/sec \t %.2f \t %s \t %s", rate, "NA", "NA"))
-	fmt.Fprintln(w, fmt.Sprintf("Latency \t %s \t %s \t %s", round(average, 2), round(stddev, 2), round(max, 2)))
 
 	if opts.Latency {
-		fmt.Fprintln(w, "Latency Distibution")
-		for _, bucket := range qbuckets {
-			quantile := quantiles[bucket]
-			fmt.Fprintln(w, fmt.Sprintf("%.2f%% \t %s", bucket*100.0, round(quantile, 2)))
+		fmt.Fprintln(w, "Latency Distribution")
+		distribution := append(append([]float64(nil), percentiles...), 100)
+		for i, value := range collector.Latency.Percentiles(distribution...) {
+			fmt.Fprintln(w, fmt.Sprintf("%s%% \t %s", strconv.FormatFloat(distribution[i], 'f', -1, 64), round(value, 2)))
 		}
 	}
 
 	if opts.Histogram {
 		fmt.Fprintln(w, "Histogram")
 		fmt.Fprintln(w, "Bucket \t Count")
-		for _, current := range hbuckets {
-			bucket := time.Duration(current)
-			fmt.Fprintln(w, fmt.Sprintf("%s \t %d", round(bucket, 2), histgram[bucket]))
-			count -= histgram[bucket]
+		var below uint64
+		for _, bucket := range buckets {
+			count := collector.Latency.CountAtOrBelow(bucket)
+			fmt.Fprintln(w, fmt.Sprintf("%s \t %d", round(bucket, 2), count-below))
+			below = count
 		}
-		fmt.Fprintln(w, fmt.Sprintf("Inf+ \t %d", count))
+		fmt.Fprintln(w, fmt.Sprintf("Inf+ \t %d", collector.Latency.Count()-below))
 	}
 
 	w.Flush()
 }
 
+// Table is a table of statistics, its first row is the header.
+type Table [][]string
+
+// Percentiles returns the percentiles of the latencies to show, 50, 90 and
+// 99 unless set.
+func Percentiles(opts options.Options) []float64 {
+	if len(opts.Percentiles) == 0 {
+		return []float64{50, 90, 99}
+	}
+	return opts.Percentiles
+}
+
+// Responses returns the number of responses of each status code, then of
+// failed requests of each error class.
+func Responses(collector *Collector) Table {
+	statuses := collector.Statuses()
+	codes := make(sort.IntSlice, 0, len(statuses))
+	for code := range statuses {
+		codes = append(codes, code)
+	}
+	codes.Sort()
+
+	headers, values := make([]string, 0), make([]string, 0)
+	for _, code := range codes {
+		headers = append(headers, fmt.Sprintf("%s: %d", http.StatusText(code), code))
+		values = append(values, strconv.FormatUint(statuses[code], 10))
+	}
+	classes := collector.Errors()
+	for _, class := range internal.ErrorClasses {
+		if classes[class] > 0 {
+			headers = append(headers, errorNames[class])
+			values = append(values, strconv.FormatUint(classes[class], 10))
+		}
+	}
+	return Table{headers, values}
+}
+
+// Statistics returns the spread of the requests per second, the latencies
+// and the phases of the requests.
+func Statistics(collector *Collector, percentiles []float64, elapsed time.Duration) Table {
+	headers := []string{"", "Min", "Average", "STDDEV", "Max"}
+	for _, p := range percentiles {
+		headers = append(headers, "P"+strconv.FormatFloat(p, 'f', -1, 64))
+	}
+	table := Table{headers}
+
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(collector.Latency.Count()) / elapsed.Seconds()
+	}
+	min, _, stddev, max := collector.Throughput.Stats()
+	row := []string{"Req/sec", fmt.Sprintf("%.2f", min), fmt.Sprintf("%.2f", rate), fmt.Sprintf("%.2f", stddev), fmt.Sprintf("%.2f", max)}
+	for _, value := range collector.Throughput.Percentiles(percentiles...) {
+		row = append(row, fmt.Sprintf("%.2f", value))
+	}
+	table = append(table, row, latency("Latency", collector.Latency, percentiles))
+	titles := map[string]string{"dns": "DNS", "connect": "Connect", "tls": "TLS", "ttfb": "TTFB", "transfer": "Transfer"}
+	for _, phase := range Phases {
+		if h := collector.Phases[phase]; h.Count() > 0 {
+			table = append(table, latency(titles[phase], h, percentiles))
+		}
+	}
+	return table
+}
+
+// columns returns the headers of the percentile columns of a table.
+func columns(percentiles []float64) string {
+	out := ""
+	for _, p := range percentiles {
+		out += " \t P" + strconv.FormatFloat(p, 'f', -1, 64)
+	}
+	return out
+}
+
+// latency returns a row of the statistics for the values of h.
+func latency(title string, h *hdr.Histogram, percentiles []float64) []string {
+	row := []string{title, round(h.Min(), 2).String(), round(h.Mean(), 2).String(), round(h.StdDev(), 2).String(), round(h.Max(), 2).String()}
+	for _, value := range h.Percentiles(percentiles...) {
+		row = append(row, round(value, 2).String())
+	}
+	return row
+}
+
 func round(d time.Duration, digits int) time.Duration {
 	var divs = []time.Duration{time.Duration(1), time.Duration(10), time.Duration(100), time.Duration(1000)}
 	switch {
@@ -160,3 +222,18 @@ func round(d time.Duration, digits int) time.Duration {
 	}
 	return d
 }
+
+var errorNames = map[string]string{
+	internal.ErrorDNS:            "DNS Failure",
+	internal.ErrorRefused:        "Connection Refused",
+	internal.ErrorReset:          "Connection Reset",
+	internal.ErrorConnectTimeout: "Connect Timeout",
+	internal.ErrorHeaderTimeout:  "Header Timeout",
+	internal.ErrorBodyTimeout:    "Body Timeout",
+	internal.ErrorTLS:            "TLS Error",
+	internal.ErrorRedirects:      "Too Many Redirects",
+	internal.ErrorBody:           "Body Read Error",
+	internal.ErrorCanceled:       "Canceled",
+	internal.ErrorAuth:           "Auth Error",
+	internal.ErrorOther:          "Errors",
+}
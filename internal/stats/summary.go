@@ -0,0 +1,232 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DustyRat/post-it/internal/hdr"
+	internal "github.com/DustyRat/post-it/internal/http"
+	"github.com/DustyRat/post-it/internal/threshold"
+)
+
+// Summary is every statistic of a run, for writing it as JSON. Durations are
+// in milliseconds.
+type Summary struct {
+	ElapsedSeconds float64 `json:"elapsed_s"`
+	Requests       uint64  `json:"requests"`
+	Responses      uint64  `json:"responses"`
+	Errors         uint64  `json:"errors"`
+	// ErrorRate is the percent of the requests that failed.
+	ErrorRate float64 `json:"error_rate"`
+
+	Statuses      map[string]uint64 `json:"statuses"`
+	StatusClasses map[string]uint64 `json:"status_classes"`
+	ErrorClasses  map[string]uint64 `json:"error_classes"`
+
+	Throughput Spread            `json:"requests_per_second"`
+	Latency    Spread            `json:"latency_ms"`
+	Phases     map[string]Spread `json:"phases_ms,omitempty"`
+
+	// Checks are the counts of the schema, compare, snapshot,
+	// snapshot_change and refresh checks that ran, by result.
+	Checks map[string]map[string]uint64 `json:"checks,omitempty"`
+	Steps  []ScenarioStep               `json:"steps,omitempty"`
+
+	Thresholds []Check `json:"thresholds,omitempty"`
+}
+
+// ScenarioStep is what happened to a step of a scenario.
+type ScenarioStep struct {
+	Name string `json:"name"`
+	// Results are the number of rows by result: ok, failed and skipped.
+	Results map[string]uint64 `json:"results"`
+	Latency Spread            `json:"latency_ms"`
+}
+
+// Spread is the spread of the values of a statistic.
+type Spread struct {
+	Min         float64            `json:"min"`
+	Mean        float64            `json:"mean"`
+	StdDev      float64            `json:"stddev"`
+	Max         float64            `json:"max"`
+	Percentiles map[string]float64 `json:"percentiles"`
+}
+
+// Check is the result of a threshold.
+type Check struct {
+	Threshold string  `json:"threshold"`
+	Value     float64 `json:"value"`
+	Breached  bool    `json:"breached"`
+}
+
+// Summarize returns the statistics of the results recorded by collector.
+func Summarize(collector *Collector, percentiles []float64, elapsed time.Duration) Summary {
+	s := Summary{
+		ElapsedSeconds: elapsed.Seconds(),
+		Statuses:       make(map[string]uint64),
+		StatusClasses:  make(map[string]uint64),
+		ErrorClasses:   make(map[string]uint64),
+		Latency:        spread(collector.Latency, percentiles),
+		Phases:         make(map[string]Spread),
+		Checks:         make(map[string]map[string]uint64),
+	}
+	for code, count := range collector.Statuses() {
+		s.Statuses[strconv.Itoa(code)] = count
+		s.StatusClasses[StatusClass(code)] += count
+		s.Responses += count
+	}
+	errors := collector.Errors()
+	for _, class := range internal.ErrorClasses {
+		s.ErrorClasses[class] = errors[class]
+		s.Errors += errors[class]
+	}
+	s.Requests = s.Responses + s.Errors
+	if s.Requests > 0 {
+		s.ErrorRate = float64(s.Errors) / float64(s.Requests) * 100
+	}
+
+	min, _, stddev, max := collector.Throughput.Stats()
+	s.Throughput = Spread{Min: min, StdDev: stddev, Max: max, Percentiles: make(map[string]float64)}
+	if elapsed > 0 {
+		s.Throughput.Mean = float64(s.Responses) / elapsed.Seconds()
+	}
+	for i, value := range collector.Throughput.Percentiles(percentiles...) {
+		s.Throughput.Percentiles[percentileName(percentiles[i])] = value
+	}
+	for _, phase := range Phases {
+		if h := collector.Phases[phase]; h.Count() > 0 {
+			s.Phases[phase] = spread(h, percentiles)
+		}
+	}
+	for _, kind := range []string{Schema, Compare, Snapshot, SnapshotChange, Refresh} {
+		if counts := collector.Checks(kind); len(counts) > 0 {
+			s.Checks[kind] = counts
+		}
+	}
+	for _, step := range collector.Steps() {
+		s.Steps = append(s.Steps, ScenarioStep{Name: step.Name, Results: step.Results, Latency: spread(step.Latency, percentiles)})
+	}
+	return s
+}
+
+func spread(h *hdr.Histogram, percentiles []float64) Spread {
+	s := Spread{Min: ms(h.Min()), Mean: ms(h.Mean()), StdDev: ms(h.StdDev()), Max: ms(h.Max()), Percentiles: make(map[string]float64)}
+	for i, value := range h.Percentiles(percentiles...) {
+		s.Percentiles[percentileName(percentiles[i])] = ms(value)
+	}
+	return s
+}
+
+// Check returns the result of each threshold against the statistics of s
+// and the latencies recorded by collector.
+func (s Summary) Check(thresholds []threshold.Threshold, collector *Collector) []Check {
+	checks := make([]Check, 0, len(thresholds))
+	for _, t := range thresholds {
+		value := s.value(t, collector)
+		checks = append(checks, Check{Threshold: t.Expr, Value: value, Breached: t.Breached(value)})
+	}
+	return checks
+}
+
+var statusCode = regexp.MustCompile(`^status_(\d+)$`)
+
+// value returns the actual value of the metric of t.
+func (s Summary) value(t threshold.Threshold, collector *Collector) float64 {
+	switch t.Metric {
+	case "requests":
+		return float64(s.Requests)
+	case "responses":
+		return float64(s.Responses)
+	case "errors":
+		return float64(s.Errors)
+	case "error_rate":
+		return s.ErrorRate
+	case "rps":
+		return s.Throughput.Mean
+	case "min":
+		return s.Latency.Min
+	case "mean":
+		return s.Latency.Mean
+	case "stddev":
+		return s.Latency.StdDev
+	case "max":
+		return s.Latency.Max
+	}
+	if p, ok := t.Percentile(); ok {
+		return ms(collector.Latency.Percentile(p))
+	}
+	if kind, result, ok := t.Check(); ok {
+		if kind == "steps" {
+			var count uint64
+			for _, step := range s.Steps {
+				count += step.Results[result]
+			}
+			return float64(count)
+		}
+		return float64(s.Checks[kind][result])
+	}
+	if match := statusCode.FindStringSubmatch(t.Metric); match != nil {
+		return float64(s.Statuses[match[1]])
+	}
+	if strings.HasPrefix(t.Metric, "status_") {
+		return float64(s.StatusClasses[strings.TrimPrefix(t.Metric, "status_")])
+	}
+	return float64(s.ErrorClasses[strings.TrimPrefix(t.Metric, "error_")])
+}
+
+// Breached returns the checks that failed.
+func Breached(checks []Check) []Check {
+	out := make([]Check, 0)
+	for _, check := range checks {
+		if check.Breached {
+			out = append(out, check)
+		}
+	}
+	return out
+}
+
+// PrintChecks prints the result of every threshold.
+func PrintChecks(w io.Writer, checks []Check) {
+	if len(checks) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Thresholds")
+	for _, check := range checks {
+		result := "ok"
+		if check.Breached {
+			result = "BREACHED"
+		}
+		fmt.Fprintf(w, "  %-8s %s (actual %s)\n", result, check.Threshold, strconv.FormatFloat(math.Round(check.Value*100)/100, 'f', -1, 64))
+	}
+}
+
+// WriteSummary writes s as JSON to path.
+func WriteSummary(path string, s Summary) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(s); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func percentileName(p float64) string {
+	return "p" + strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,131 @@
+package stats
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DustyRat/post-it/internal/file/csv"
+	"github.com/DustyRat/post-it/internal/hdr"
+	internal "github.com/DustyRat/post-it/internal/http"
+)
+
+// statusClasses are the status classes of the time series columns.
+var statusClasses = []string{"1xx", "2xx", "3xx", "4xx", "5xx"}
+
+// Timeseries writes a row of the throughput and latency of the requests of
+// every interval of the run to a CSV file.
+type Timeseries struct {
+	mutex    sync.Mutex
+	writer   *csv.Writer
+	start    time.Time
+	window   *window
+	stop     chan struct{}
+	done     chan struct{}
+	interval time.Duration
+}
+
+// window is what happened in an interval.
+type window struct {
+	sent      uint64
+	completed uint64
+	statuses  map[string]uint64
+	errors    map[string]uint64
+	latency   *hdr.Histogram
+	in, out   int64
+}
+
+func newWindow() *window {
+	return &window{statuses: make(map[string]uint64), errors: make(map[string]uint64), latency: hdr.New()}
+}
+
+// NewTimeseries writes a row every interval to the CSV file at path.
+func NewTimeseries(path string, interval time.Duration) (*Timeseries, error) {
+	writer, err := csv.NewWriter(path)
+	if err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	headers := []string{"time", "elapsed_s", "sent", "completed"}
+	headers = append(headers, statusClasses...)
+	headers = append(headers, internal.ErrorClasses...)
+	headers = append(headers, "p50_ms", "p90_ms", "p99_ms", "max_ms", "bytes_in", "bytes_out")
+	writer.Write(headers)
+
+	t := &Timeseries{writer: writer, start: time.Now(), window: newWindow(), stop: make(chan struct{}), done: make(chan struct{}), interval: interval}
+	go func() {
+		defer close(t.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				t.write(now)
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+	return t, nil
+}
+
+// Sending implements internal.Recorder.
+func (t *Timeseries) Sending() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.window.sent++
+}
+
+// Record implements internal.Recorder.
+func (t *Timeseries) Record(result internal.Result) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	w := t.window
+	w.completed++
+	if result.Class != "" {
+		w.errors[result.Class]++
+	} else {
+		w.statuses[StatusClass(result.StatusCode)]++
+		w.latency.Record(result.Duration)
+	}
+	w.in += result.Received
+	w.out += result.Sent
+}
+
+// write ends the current interval at now and writes its row.
+func (t *Timeseries) write(now time.Time) {
+	t.mutex.Lock()
+	w := t.window
+	t.window = newWindow()
+	t.mutex.Unlock()
+
+	row := []string{
+		now.Format(time.RFC3339),
+		strconv.FormatFloat(now.Sub(t.start).Seconds(), 'f', 3, 64),
+		strconv.FormatUint(w.sent, 10),
+		strconv.FormatUint(w.completed, 10),
+	}
+	for _, class := range statusClasses {
+		row = append(row, strconv.FormatUint(w.statuses[class], 10))
+	}
+	for _, class := range internal.ErrorClasses {
+		row = append(row, strconv.FormatUint(w.errors[class], 10))
+	}
+	for _, value := range append(w.latency.Percentiles(50, 90, 99), w.latency.Max()) {
+		row = append(row, strconv.FormatFloat(value.Seconds()*1000, 'f', 3, 64))
+	}
+	row = append(row, strconv.FormatInt(w.in, 10), strconv.FormatInt(w.out, 10))
+	t.writer.Write(row)
+	t.writer.Flush()
+}
+
+// Close writes the row of the last, partial, interval.
+func (t *Timeseries) Close() error {
+	close(t.stop)
+	<-t.done
+	t.write(time.Now())
+	return nil
+}
@@ -0,0 +1,98 @@
+package suite
+
+import (
+	"encoding/xml"
+	"os"
+	"strconv"
+	"time"
+)
+
+type testsuites struct {
+	XMLName  xml.Name    `xml:"testsuites"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Suites   []testsuite `xml:"testsuite"`
+}
+
+type testsuite struct {
+	Name      string     `xml:"name,attr"`
+	Tests     int        `xml:"tests,attr"`
+	Failures  int        `xml:"failures,attr"`
+	Errors    int        `xml:"errors,attr"`
+	Time      string     `xml:"time,attr"`
+	Timestamp string     `xml:"timestamp,attr"`
+	Cases     []testcase `xml:"testcase"`
+}
+
+type testcase struct {
+	Name      string   `xml:"name,attr"`
+	Classname string   `xml:"classname,attr"`
+	Time      string   `xml:"time,attr"`
+	Failure   *problem `xml:"failure"`
+	Error     *problem `xml:"error"`
+}
+
+type problem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",cdata"`
+}
+
+// WriteJUnit writes the cases as a JUnit XML test suite named name to path.
+func WriteJUnit(path, name string, cases []*Case, started time.Time, elapsed time.Duration) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	s := testsuite{Name: name, Tests: len(cases), Time: seconds(elapsed), Timestamp: started.Format("2006-01-02T15:04:05")}
+	for _, c := range cases {
+		tc := testcase{Name: c.Name, Classname: name, Time: seconds(c.Duration)}
+		if len(c.Errors) > 0 {
+			tc.Error = merge(c.Errors)
+			s.Errors++
+		} else if len(c.Failures) > 0 {
+			tc.Failure = merge(c.Failures)
+			s.Failures++
+		}
+		s.Cases = append(s.Cases, tc)
+	}
+	doc := testsuites{Name: name, Tests: s.Tests, Failures: s.Failures, Errors: s.Errors, Time: s.Time, Suites: []testsuite{s}}
+
+	if _, err := file.WriteString(xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(file)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	if _, err := file.WriteString("\n"); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// merge makes a single element of the problems of a case, JUnit only has
+// one per case: the message of the first and the output of them all.
+func merge(problems []Problem) *problem {
+	p := &problem{Message: problems[0].Message, Type: problems[0].Type}
+	for i, pr := range problems {
+		if i > 0 {
+			p.Text += "\n\n"
+		}
+		p.Text += pr.Type + ": " + pr.Message
+		if pr.Output != "" {
+			p.Text += "\n" + pr.Output
+		}
+	}
+	return p
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
@@ -0,0 +1,129 @@
+// Package suite turns the input rows of a run into test cases, written as
+// JUnit XML or TAP for CI test dashboards.
+package suite
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	internal "github.com/DustyRat/post-it/internal/http"
+)
+
+// snippet is the number of bytes of a response body kept in a problem.
+const snippet = 512
+
+// Problem is why a case didn't pass.
+type Problem struct {
+	// Type is what went wrong, e.g. status, schema or the class of a
+	// transport error.
+	Type    string
+	Message string
+	// Output is a snippet of the response body.
+	Output string
+}
+
+// Outcome is the result of an input row.
+type Outcome struct {
+	// Row is the number of the row in the input file, from 1.
+	Row      int
+	Fields   map[string]string
+	Method   string
+	URL      string
+	Duration time.Duration
+
+	// Failures are responses that didn't match what was expected, Errors
+	// are requests that got no response.
+	Failures []Problem
+	Errors   []Problem
+}
+
+// Case is a test case, of a row or of the rows of a group.
+type Case struct {
+	Name     string
+	Rows     int
+	Duration time.Duration
+	Failures []Problem
+	Errors   []Problem
+
+	row int
+}
+
+// Passed reports whether the case had neither failures nor errors.
+func (c *Case) Passed() bool {
+	return len(c.Failures) == 0 && len(c.Errors) == 0
+}
+
+// Suite collects the outcome of every row. It's safe for concurrent use.
+type Suite struct {
+	// Name is the template of the names of the cases, it may use the input
+	// columns, {method}, {url} and {row}.
+	Name string
+	// Group, when set, is the column whose rows make a single case.
+	Group string
+
+	mutex sync.Mutex
+	cases map[string]*Case
+}
+
+// New ...
+func New(name, group string) *Suite {
+	if name == "" {
+		name = "{method} {url}"
+	}
+	return &Suite{Name: name, Group: group, cases: make(map[string]*Case)}
+}
+
+// Add adds the outcome of a row to its case.
+func (s *Suite) Add(o Outcome) {
+	fields := make(map[string]string, len(o.Fields)+3)
+	for k, v := range o.Fields {
+		fields[k] = v
+	}
+	fields["method"] = o.Method
+	fields["url"] = o.URL
+	fields["row"] = strconv.Itoa(o.Row)
+	name := strings.TrimSpace(internal.Expand(s.Name, fields))
+
+	key := "row " + strconv.Itoa(o.Row)
+	if s.Group != "" {
+		key = "group " + o.Fields[s.Group]
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	c, ok := s.cases[key]
+	if !ok {
+		c = &Case{Name: name, row: o.Row}
+		s.cases[key] = c
+	}
+	if o.Row < c.row {
+		c.Name, c.row = name, o.Row
+	}
+	c.Rows++
+	c.Duration += o.Duration
+	c.Failures = append(c.Failures, o.Failures...)
+	c.Errors = append(c.Errors, o.Errors...)
+}
+
+// Cases returns the cases in the order of their first row.
+func (s *Suite) Cases() []*Case {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	out := make([]*Case, 0, len(s.cases))
+	for _, c := range s.cases {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].row < out[j].row })
+	return out
+}
+
+// Snippet returns the start of a response body for the output of a problem.
+func Snippet(body []byte) string {
+	if len(body) > snippet {
+		return string(body[:snippet]) + "..."
+	}
+	return string(body)
+}
@@ -0,0 +1,55 @@
+package suite
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// WriteTAP writes the cases as a TAP version 13 stream to path, with the
+// problems of the failed cases in a YAML block.
+func WriteTAP(path string, cases []*Case) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	fmt.Fprintln(w, "TAP version 13")
+	fmt.Fprintf(w, "1..%d\n", len(cases))
+	for i, c := range cases {
+		name := strings.Replace(c.Name, "#", "\\#", -1)
+		if c.Passed() {
+			fmt.Fprintf(w, "ok %d - %s\n", i+1, name)
+			continue
+		}
+		fmt.Fprintf(w, "not ok %d - %s\n", i+1, name)
+		severity, problems := "fail", c.Failures
+		if len(c.Errors) > 0 {
+			severity, problems = "error", append(append([]Problem(nil), c.Errors...), c.Failures...)
+		}
+		fmt.Fprintln(w, "  ---")
+		fmt.Fprintf(w, "  message: %s\n", strconv.Quote(problems[0].Message))
+		fmt.Fprintf(w, "  severity: %s\n", severity)
+		fmt.Fprintf(w, "  duration_ms: %s\n", strconv.FormatFloat(c.Duration.Seconds()*1000, 'f', 3, 64))
+		fmt.Fprintln(w, "  problems:")
+		for _, p := range problems {
+			fmt.Fprintf(w, "    - type: %s\n", p.Type)
+			fmt.Fprintf(w, "      message: %s\n", strconv.Quote(p.Message))
+			if p.Output != "" {
+				fmt.Fprintln(w, "      output: |")
+				for _, line := range strings.Split(p.Output, "\n") {
+					fmt.Fprintf(w, "        %s\n", line)
+				}
+			}
+		}
+		fmt.Fprintln(w, "  ...")
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,177 @@
+// Package threshold parses the conditions on the statistics of a run that
+// fail it, e.g. p99>800ms, so CI pipelines can gate on the exit code.
+package threshold
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	internal "github.com/DustyRat/post-it/internal/http"
+)
+
+// Kinds of metrics, which decide the unit of their values.
+const (
+	// Count metrics are numbers of requests, e.g. errors or status_5xx.
+	Count = "count"
+	// Percent metrics are percentages of the requests, e.g. error_rate.
+	Percent = "percent"
+	// Rate metrics are requests per second, e.g. rps.
+	Rate = "rate"
+	// Duration metrics are latencies in milliseconds, e.g. p99.
+	Duration = "duration"
+)
+
+var (
+	expression = regexp.MustCompile(`^\s*([a-z0-9_.]+)\s*(>=|<=|==|!=|>|<)\s*(\S+)\s*$`)
+	percentile = regexp.MustCompile(`^p(\d+(\.\d+)?)$`)
+	status     = regexp.MustCompile(`^status_([1-5](xx|\d\d))$`)
+)
+
+// checks are the results of the checks of a run by kind, counted by the
+// <kind>_<result> metrics, e.g. schema_invalid.
+var checks = map[string][]string{
+	"schema":          {"valid", "invalid", "violations"},
+	"compare":         {"match", "mismatch", "error"},
+	"snapshot":        {"recorded", "matched", "changed", "missing", "updated", "error"},
+	"snapshot_change": {"added", "removed", "changed"},
+	"refresh":         {"ok", "failed"},
+	"steps":           {"ok", "failed", "skipped"},
+}
+
+// Threshold ...
+type Threshold struct {
+	// Expr is the condition as given, e.g. p99>800ms.
+	Expr   string
+	Metric string
+	Kind   string
+	Op     string
+	// Value is in the unit of the kind: milliseconds for durations and
+	// percent for percentages.
+	Value float64
+}
+
+// Parse parses a condition such as error_rate>1%, p99>800ms or
+// status_5xx>0. The metrics are:
+//
+//	requests, responses, errors    number of requests, responses and failed requests
+//	error_rate                     percent of the requests that failed
+//	rps                            responses per second
+//	min, mean, stddev, max, p<N>   latencies of the responses, e.g. p99.9
+//	status_<N>                     number of responses of a status or class, e.g. status_404, status_5xx
+//	error_<class>                  number of failed requests of an error class, e.g. error_dns
+//	schema_<result>                number of bodies valid or invalid against the schema, or of violations
+//	compare_<result>               number of comparisons that match, mismatch or failed with an error
+//	snapshot_<result>              number of snapshots recorded, matched, changed, missing, updated or failed with an error
+//	snapshot_change_<kind>         number of fields added, removed or changed from their snapshot
+//	refresh_<result>               number of credential refreshes that were ok or failed
+//	steps_<result>                 number of scenario steps that were ok, failed or skipped
+func Parse(expr string) (Threshold, error) {
+	match := expression.FindStringSubmatch(expr)
+	if match == nil {
+		return Threshold{}, fmt.Errorf("invalid threshold %q, expected <metric><op><value>, eg: p99>800ms", expr)
+	}
+	t := Threshold{Expr: strings.TrimSpace(expr), Metric: match[1], Op: match[2]}
+	t.Kind = kind(t.Metric)
+	if t.Kind == "" {
+		return Threshold{}, fmt.Errorf("invalid threshold %q, unknown metric %s", expr, t.Metric)
+	}
+
+	raw := match[3]
+	var err error
+	switch {
+	case t.Kind == Duration && strings.IndexFunc(raw, isLetter) >= 0:
+		var d time.Duration
+		d, err = time.ParseDuration(raw)
+		t.Value = float64(d) / float64(time.Millisecond)
+	case t.Kind == Percent:
+		t.Value, err = strconv.ParseFloat(strings.TrimSuffix(raw, "%"), 64)
+	default:
+		t.Value, err = strconv.ParseFloat(raw, 64)
+	}
+	if err != nil {
+		return Threshold{}, fmt.Errorf("invalid threshold %q, bad %s value %s", expr, t.Kind, raw)
+	}
+	return t, nil
+}
+
+// kind returns the kind of a metric, or "" when it's unknown.
+func kind(metric string) string {
+	switch metric {
+	case "requests", "responses", "errors":
+		return Count
+	case "error_rate":
+		return Percent
+	case "rps":
+		return Rate
+	case "min", "mean", "stddev", "max":
+		return Duration
+	}
+	if match := percentile.FindStringSubmatch(metric); match != nil {
+		if p, _ := strconv.ParseFloat(match[1], 64); p <= 100 {
+			return Duration
+		}
+		return ""
+	}
+	if status.MatchString(metric) {
+		return Count
+	}
+	for _, class := range internal.ErrorClasses {
+		if metric == "error_"+class {
+			return Count
+		}
+	}
+	if _, _, ok := (Threshold{Metric: metric}).Check(); ok {
+		return Count
+	}
+	return ""
+}
+
+// Percentile returns the percentile of a p<N> metric.
+func (t Threshold) Percentile() (float64, bool) {
+	match := percentile.FindStringSubmatch(t.Metric)
+	if match == nil {
+		return 0, false
+	}
+	p, _ := strconv.ParseFloat(match[1], 64)
+	return p, true
+}
+
+// Check returns the kind and result of a <kind>_<result> metric of the
+// checks of a run, e.g. schema and invalid for schema_invalid.
+func (t Threshold) Check() (string, string, bool) {
+	for kind, results := range checks {
+		for _, result := range results {
+			if t.Metric == kind+"_"+result {
+				return kind, result, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// Breached reports whether the actual value of the metric breaches the
+// threshold.
+func (t Threshold) Breached(actual float64) bool {
+	switch t.Op {
+	case ">":
+		return actual > t.Value
+	case ">=":
+		return actual >= t.Value
+	case "<":
+		return actual < t.Value
+	case "<=":
+		return actual <= t.Value
+	case "==":
+		return actual == t.Value
+	case "!=":
+		return actual != t.Value
+	}
+	return false
+}
+
+func isLetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == 'µ'
+}
@@ -0,0 +1,83 @@
+package worker
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/DustyRat/post-it/internal/diff"
+	internal "github.com/DustyRat/post-it/internal/http"
+	"github.com/DustyRat/post-it/internal/jsonpath"
+	"github.com/DustyRat/post-it/internal/stats"
+)
+
+// Comparison sends every request to a second target as well and compares
+// status codes, the selected headers and normalized bodies.
+type Comparison struct {
+	Client  *internal.Client
+	URL     *url.URL
+	Headers []string
+	Ignore  []*jsonpath.Path
+}
+
+type comparison struct {
+	response *internal.Response
+	err      error
+	diffs    []string
+}
+
+func (c *Comparison) compare(collector *stats.Collector, request *internal.Request, body []byte, a *internal.Response, aErr error) *comparison {
+	session := c.Client.Session()
+	b, err := session.Send(&internal.Request{
+		Method: request.Method,
+		Header: c.Client.Unconfigured(request.Header),
+		URL:    internal.Rebase(c.URL, request.URL),
+		Body:   bytes.NewReader(body),
+		Fields: request.Fields,
+	})
+	session.Close()
+	result := &comparison{response: b, err: err, diffs: make([]string, 0)}
+
+	if a != nil {
+		collector.Comparison["a"].Record(a.Duration)
+	}
+	if b != nil {
+		collector.Comparison["b"].Record(b.Duration)
+	}
+
+	switch {
+	case aErr != nil || err != nil:
+		if aErr != nil {
+			result.diffs = append(result.diffs, fmt.Sprintf("error a: %s", aErr))
+		}
+		if err != nil {
+			result.diffs = append(result.diffs, fmt.Sprintf("error b: %s", err))
+		}
+		collector.Compared("error")
+		return result
+	case a == nil || b == nil:
+		collector.Compared("error")
+		return result
+	}
+
+	if a.StatusCode != b.StatusCode {
+		result.diffs = append(result.diffs, fmt.Sprintf("status: %d != %d", a.StatusCode, b.StatusCode))
+	}
+	for _, header := range c.Headers {
+		x, y := strings.Join(a.Header.Values(header), ", "), strings.Join(b.Header.Values(header), ", ")
+		if x != y {
+			result.diffs = append(result.diffs, fmt.Sprintf("header %s: %q != %q", header, x, y))
+		}
+	}
+	for _, change := range diff.Bodies(a.Body, b.Body, c.Ignore) {
+		result.diffs = append(result.diffs, change.String())
+	}
+
+	if len(result.diffs) == 0 {
+		collector.Compared("match")
+	} else {
+		collector.Compared("mismatch")
+	}
+	return result
+}
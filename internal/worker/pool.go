@@ -6,7 +6,12 @@ import (
 
 	"github.com/DustyRat/post-it/internal/file/csv"
 	"github.com/DustyRat/post-it/internal/http"
+	"github.com/DustyRat/post-it/internal/openapi"
 	"github.com/DustyRat/post-it/internal/options"
+	"github.com/DustyRat/post-it/internal/scenario"
+	"github.com/DustyRat/post-it/internal/schema"
+	"github.com/DustyRat/post-it/internal/stats"
+	"github.com/DustyRat/post-it/internal/suite"
 
 	"github.com/goinggo/work"
 	"github.com/vbauerster/mpb/v5"
@@ -16,9 +21,11 @@ import (
 // Pool ...
 type Pool struct {
 	requests int64
+	total    int64
 
 	options *options.Options
 	client  *http.Client
+	stats   *stats.Collector
 
 	progress *mpb.Progress
 	bar      *mpb.Bar
@@ -29,13 +36,22 @@ type Pool struct {
 	reader *csv.Reader
 	writer *csv.Writer
 	mux    *sync.Mutex
+
+	schema *schema.Schema
+	spec   *openapi.Spec
+
+	comparison *Comparison
+	snapshots  *Snapshots
+	scenario   *scenario.Scenario
+	suite      *suite.Suite
 }
 
 // NewPool ...
-func NewPool(opts *options.Options, pool *work.Pool, client *http.Client, progress *mpb.Progress, total int, reader *csv.Reader, writer *csv.Writer) *Pool {
+func NewPool(opts *options.Options, pool *work.Pool, client *http.Client, collector *stats.Collector, progress *mpb.Progress, total int, reader *csv.Reader, writer *csv.Writer) *Pool {
 	return &Pool{
 		options:  opts,
 		client:   client,
+		stats:    collector,
 		progress: progress,
 		bar: progress.AddBar(int64(total),
 			mpb.BarID(0),
@@ -51,6 +67,7 @@ func NewPool(opts *options.Options, pool *work.Pool, client *http.Client, progre
 				decor.OnComplete(decor.AverageETA(decor.ET_STYLE_GO, decor.WCSyncSpaceR), ""),
 			),
 		),
+		total:  int64(total),
 		pool:   pool,
 		reader: reader,
 		writer: writer,
@@ -58,6 +75,36 @@ func NewPool(opts *options.Options, pool *work.Pool, client *http.Client, progre
 	}
 }
 
+// SetSchema validates every response body against s.
+func (p *Pool) SetSchema(s *schema.Schema) {
+	p.schema = s
+}
+
+// SetSpec validates every request and response against the OpenAPI spec.
+func (p *Pool) SetSpec(spec *openapi.Spec) {
+	p.spec = spec
+}
+
+// SetComparison sends every request to a second target and compares the responses.
+func (p *Pool) SetComparison(c *Comparison) {
+	p.comparison = c
+}
+
+// SetSnapshots records or verifies a snapshot of every response.
+func (p *Pool) SetSnapshots(s *Snapshots) {
+	p.snapshots = s
+}
+
+// SetScenario runs the steps of s for every row instead of a single request.
+func (p *Pool) SetScenario(s *scenario.Scenario) {
+	p.scenario = s
+}
+
+// SetSuite adds the outcome of every row to a test suite.
+func (p *Pool) SetSuite(s *suite.Suite) {
+	p.suite = s
+}
+
 // NewWorker ...
 func (p *Pool) NewWorker() *worker {
 	p.mux.Lock()
@@ -88,3 +135,10 @@ func (p *Pool) increment() {
 	defer p.mux.Unlock()
 	p.requests++
 }
+
+// Remaining returns the number of rows not done yet.
+func (p *Pool) Remaining() int64 {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.total - p.requests
+}
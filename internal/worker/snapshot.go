@@ -0,0 +1,44 @@
+package worker
+
+import (
+	"fmt"
+	"strings"
+
+	internal "github.com/DustyRat/post-it/internal/http"
+	"github.com/DustyRat/post-it/internal/snapshot"
+	"github.com/DustyRat/post-it/internal/stats"
+)
+
+// Snapshots records or verifies a snapshot of every response, keyed by the
+// value of the Key column.
+type Snapshots struct {
+	Store *snapshot.Store
+	Key   string
+}
+
+func (s *Snapshots) check(collector *stats.Collector, fields map[string]string, response *internal.Response, err error) string {
+	if err != nil {
+		collector.Snapshotted("error", nil)
+		return fmt.Sprintf("error: %s", err)
+	}
+	if response == nil {
+		collector.Snapshotted("error", nil)
+		return "error: no response"
+	}
+
+	result, changes, err := s.Store.Check(fields[s.Key], s.Store.Normalize(response.StatusCode, response.Body))
+	if err != nil {
+		collector.Snapshotted("error", nil)
+		return fmt.Sprintf("error: %s", err)
+	}
+	out, kinds := make([]string, 0, len(changes)), make([]string, 0, len(changes))
+	for _, change := range changes {
+		out = append(out, change.String())
+		kinds = append(kinds, string(change.Kind))
+	}
+	collector.Snapshotted(string(result), kinds)
+	if len(out) == 0 {
+		return string(result)
+	}
+	return fmt.Sprintf("%s: %s", result, strings.Join(out, "; "))
+}
@@ -0,0 +1,84 @@
+package worker
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	internal "github.com/DustyRat/post-it/internal/http"
+	"github.com/DustyRat/post-it/internal/snapshot"
+	"github.com/DustyRat/post-it/internal/suite"
+)
+
+// outcome returns the test outcome of the entry of a row. Requests without a
+// response are errors; responses not matching status, or failing a scenario
+// step, the schema, the OpenAPI spec, the comparison or the snapshot are
+// failures.
+func (e *entry) outcome(row int, status string) suite.Outcome {
+	o := suite.Outcome{Row: row, Fields: e.record.Fields}
+	request := e.request
+	o.Method = request.Method
+	if request.URL != nil {
+		o.URL = request.URL.String()
+	}
+	response := request.Response
+	var body string
+	if response != nil {
+		body = suite.Snippet(response.Body)
+	}
+
+	if e.scenario != nil {
+		var duration time.Duration
+		for _, step := range e.scenario.Steps {
+			if step.Response != nil {
+				duration += step.Response.Duration
+			}
+		}
+		o.Duration = duration
+		if failed := e.scenario.Failed; failed != nil {
+			problem := suite.Problem{Message: fmt.Sprintf("step %s: %s", failed.Name, e.err), Output: body}
+			if response == nil {
+				problem.Type = internal.Classify(e.err)
+				o.Errors = append(o.Errors, problem)
+			} else {
+				problem.Type = "scenario"
+				o.Failures = append(o.Failures, problem)
+			}
+		}
+	} else if response != nil {
+		o.Duration = response.Duration
+		if e.err != nil {
+			o.Errors = append(o.Errors, suite.Problem{Type: internal.Classify(e.err), Message: e.err.Error(), Output: body})
+		} else if !internal.MatchStatus(status, response.StatusCode) {
+			o.Failures = append(o.Failures, suite.Problem{Type: "status", Message: fmt.Sprintf("status %d does not match %s", response.StatusCode, status), Output: body})
+		}
+	} else {
+		problem := suite.Problem{Type: internal.ErrorOther, Message: "no response"}
+		if e.err != nil {
+			problem.Type, problem.Message = internal.Classify(e.err), e.err.Error()
+		}
+		o.Errors = append(o.Errors, problem)
+	}
+
+	if len(e.violations) > 0 {
+		violations := make([]string, 0, len(e.violations))
+		for _, violation := range e.violations {
+			violations = append(violations, violation.String())
+		}
+		o.Failures = append(o.Failures, suite.Problem{Type: "schema", Message: strings.Join(violations, "; "), Output: body})
+	}
+	if len(e.contract) > 0 {
+		violations := make([]string, 0, len(e.contract))
+		for _, violation := range e.contract {
+			violations = append(violations, violation.String())
+		}
+		o.Failures = append(o.Failures, suite.Problem{Type: "openapi", Message: strings.Join(violations, "; "), Output: body})
+	}
+	if e.comparison != nil && len(e.comparison.diffs) > 0 {
+		o.Failures = append(o.Failures, suite.Problem{Type: "compare", Message: strings.Join(e.comparison.diffs, "; "), Output: body})
+	}
+	if e.snapshot != "" && !strings.HasPrefix(e.snapshot, string(snapshot.Matched)) && !strings.HasPrefix(e.snapshot, string(snapshot.Recorded)) && !strings.HasPrefix(e.snapshot, string(snapshot.Updated)) {
+		o.Failures = append(o.Failures, suite.Problem{Type: "snapshot", Message: e.snapshot, Output: body})
+	}
+	return o
+}
@@ -1,34 +1,28 @@
 package worker
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"net/http"
-	"regexp"
 	"runtime/debug"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/DustyRat/post-it/internal/file/csv"
 	internal "github.com/DustyRat/post-it/internal/http"
+	"github.com/DustyRat/post-it/internal/openapi"
 	"github.com/DustyRat/post-it/internal/options"
+	"github.com/DustyRat/post-it/internal/scenario"
+	"github.com/DustyRat/post-it/internal/schema"
 
 	"github.com/vbauerster/mpb/v5"
 
 	log "github.com/sirupsen/logrus"
 )
 
-var (
-	statusDxx *regexp.Regexp
-	statusDdd *regexp.Regexp
-)
-
-func init() {
-	statusDxx = regexp.MustCompile("^-?\\dxx")
-	statusDdd = regexp.MustCompile("\\d{3}")
-}
-
 type worker struct {
 	id      int
 	record  *csv.Record
@@ -39,9 +33,14 @@ type worker struct {
 }
 
 type entry struct {
-	record  *csv.Record
-	request *internal.Request
-	err     error
+	record     *csv.Record
+	request    *internal.Request
+	violations []schema.Violation
+	contract   []openapi.Violation
+	comparison *comparison
+	snapshot   string
+	scenario   *scenario.Result
+	err        error
 }
 
 // Strings ...
@@ -57,6 +56,19 @@ func (e *entry) Strings(flags options.Flags) []string {
 	response := e.request.Response
 	if response != nil {
 		out = append(out, strconv.Itoa(response.StatusCode))
+	} else {
+		out = append(out, "0")
+	}
+
+	if flags.Compare {
+		if e.comparison != nil && e.comparison.response != nil {
+			out = append(out, strconv.Itoa(e.comparison.response.StatusCode))
+		} else {
+			out = append(out, "0")
+		}
+	}
+
+	if response != nil {
 		if flags.Headers {
 			out = append(out, toString(response.Header))
 		}
@@ -64,7 +76,6 @@ func (e *entry) Strings(flags options.Flags) []string {
 			out = append(out, string(response.Body))
 		}
 	} else {
-		out = append(out, "0")
 		if flags.Headers {
 			out = append(out, "")
 		}
@@ -73,11 +84,58 @@ func (e *entry) Strings(flags options.Flags) []string {
 		}
 	}
 
+	if flags.Timings {
+		var timings internal.Timings
+		if response != nil {
+			timings = response.Timings
+		}
+		for _, d := range []time.Duration{timings.DNS, timings.Connect, timings.TLS, timings.TTFB, timings.Transfer} {
+			out = append(out, strconv.FormatFloat(d.Seconds()*1000, 'f', 2, 64))
+		}
+		out = append(out, strconv.FormatBool(timings.Reused))
+	}
+
+	if flags.Schema {
+		violations := make([]string, 0, len(e.violations))
+		for _, violation := range e.violations {
+			violations = append(violations, violation.String())
+		}
+		out = append(out, strings.Join(violations, "; "))
+	}
+
+	if flags.OpenAPI {
+		violations := make([]string, 0, len(e.contract))
+		for _, violation := range e.contract {
+			violations = append(violations, violation.String())
+		}
+		out = append(out, strings.Join(violations, "; "))
+	}
+
+	if flags.Compare {
+		if e.comparison != nil {
+			out = append(out, strings.Join(e.comparison.diffs, "; "))
+		} else {
+			out = append(out, "")
+		}
+	}
+
+	if flags.Snapshot {
+		out = append(out, e.snapshot)
+	}
+
+	if flags.Scenario {
+		if e.scenario != nil && e.scenario.Failed != nil {
+			out = append(out, e.scenario.Failed.Name)
+		} else {
+			out = append(out, "")
+		}
+	}
+
 	if flags.Errors {
 		if e.err != nil {
-			out = append(out, e.err.Error())
+			out = append(out, e.err.Error(), internal.Classify(e.err))
 		} else {
-			out = append(out, "")
+			out = append(out, "", "")
 		}
 	}
 
@@ -90,17 +148,72 @@ func (w *worker) Work(id int) {
 	entry := &entry{record: w.record, request: w.request}
 	defer func() {
 		defer w.done()
+		if w.pool.suite != nil {
+			w.pool.suite.Add(entry.outcome(w.record.Row, w.pool.options.Suite.Status))
+		}
 		go write(w.pool.writer, *w.pool.options, *entry)
 		w.progress.Increment()
 		w.pool.increment()
 	}()
 
-	response, err := w.pool.client.Do(w.request.Method, w.request.URL, w.request.Header, w.request.Body)
+	request, body := w.request, w.record.Body
+	client := w.pool.client.Session()
+	var response *internal.Response
+	var err error
+	if w.pool.scenario != nil {
+		entry.scenario = w.pool.scenario.Run(client, w.pool.stats, w.record.Fields)
+		last := entry.scenario.Last()
+		if last.Request != nil {
+			request, body = last.Request, last.Body
+		}
+		response, err = last.Response, last.Err
+	} else {
+		response, err = client.Send(request)
+		request.Response = response
+	}
+	client.Close()
 	if err != nil {
 		entry.err = err
 	}
 
-	entry.request.Response = response
+	entry.request = request
+	if w.pool.comparison != nil {
+		entry.comparison = w.pool.comparison.compare(w.pool.stats, request, body, response, err)
+	}
+	if w.pool.snapshots != nil {
+		entry.snapshot = w.pool.snapshots.check(w.pool.stats, w.record.Fields, response, err)
+	}
+	// Empty bodies, e.g. of 204 responses, have nothing to validate.
+	if w.pool.schema != nil && err == nil && response != nil && len(bytes.TrimSpace(response.Body)) > 0 {
+		entry.violations = w.pool.schema.Validate(response.Body)
+		w.pool.stats.Validated(len(entry.violations))
+	}
+	if w.pool.spec != nil {
+		if entry.scenario != nil {
+			for _, step := range entry.scenario.Steps {
+				if step.Request != nil {
+					entry.contract = append(entry.contract, w.validate(step.Request, step.Body)...)
+				}
+			}
+		} else {
+			entry.contract = w.validate(request, body)
+		}
+	}
+}
+
+func (w *worker) validate(request *internal.Request, body []byte) []openapi.Violation {
+	exchange := openapi.Exchange{
+		Method: request.Method,
+		URL:    request.URL,
+		Header: request.Header,
+		Body:   body,
+	}
+	if response := request.Response; response != nil {
+		exchange.StatusCode = response.StatusCode
+		exchange.ResponseHeader = response.Header
+		exchange.ResponseBody = response.Body
+	}
+	return w.pool.spec.Validate(exchange)
 }
 
 func write(w *csv.Writer, opts options.Options, entry entry) {
@@ -110,39 +223,16 @@ func write(w *csv.Writer, opts options.Options, entry entry) {
 	defer w.Flush()
 
 	request := entry.request
-	response := request.Response
+	code := 0
+	if request.Response != nil {
+		code = request.Response.StatusCode
+	}
 
-	if opts.Flags.Status == "any" {
+	// Rows are written when their status matches, e.g. any, 2xx,404 or
+	// -2xx, or with --errors when their request failed, e.g. with none.
+	if internal.MatchStatus(opts.Flags.Status, code) || opts.Flags.Errors && entry.err != nil {
 		output := entry.Strings(opts.Flags)
 		w.Write(output)
-	} else if statusDxx.MatchString(opts.Flags.Status) {
-		status, _ := strconv.Atoi(strings.Replace(opts.Flags.Status, "xx", "00", 1))
-		if status > 0 {
-			a := status
-			b := a + 100
-			if internal.InRange(response.StatusCode, a, b) {
-				output := entry.Strings(opts.Flags)
-				w.Write(output)
-			}
-		} else if status < 0 {
-			a := -status
-			b := a + 100
-			if !internal.InRange(response.StatusCode, a, b) {
-				output := entry.Strings(opts.Flags)
-				w.Write(output)
-			}
-		}
-	} else if statusDdd.MatchString(opts.Flags.Status) {
-		status, _ := strconv.Atoi(opts.Flags.Status)
-		if response.StatusCode == status {
-			output := entry.Strings(opts.Flags)
-			w.Write(output)
-		}
-	} else if opts.Flags.Errors {
-		if entry.err != nil {
-			output := entry.Strings(opts.Flags)
-			w.Write(output)
-		}
 	}
 }
 
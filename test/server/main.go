@@ -1,12 +1,20 @@
 package main
 
 import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"math/rand"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -31,6 +39,14 @@ func run() error {
 	router.Handle("/post/{id}", post()).Methods(http.MethodPost)
 	router.Handle("/delete/{id}", delete()).Methods(http.MethodDelete)
 	router.Handle("/head/{id}", head()).Methods(http.MethodHead)
+	router.Handle("/login", login()).Methods(http.MethodPost)
+	router.Handle("/logout", logout()).Methods(http.MethodPost)
+	router.Handle("/private/{id}", private()).Methods(http.MethodGet)
+	router.Handle("/oauth/token", oauthToken()).Methods(http.MethodPost)
+	router.Handle("/signed/{id}", signed()).Methods(http.MethodGet, http.MethodPost)
+	router.Handle("/basic/{id}", basic()).Methods(http.MethodGet)
+	router.Handle("/cookies", cookies()).Methods(http.MethodGet)
+	router.Handle("/digest/{id}", digest()).Methods(http.MethodGet)
 
 	srv := http.Server{
 		Addr:         "localhost:3000",
@@ -289,6 +305,211 @@ func delete() http.HandlerFunc {
 	}
 }
 
+// tokens maps issued tokens to the number of requests they are still valid for.
+var tokens = struct {
+	sync.Mutex
+	uses map[string]int
+}{uses: make(map[string]int)}
+
+func login() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer close(w, r)
+		token := strconv.FormatInt(rand.Int63(), 36)
+		log.Info().Str("handler", "login").Str("token", token).Send()
+
+		tokens.Lock()
+		tokens.uses[token] = 3
+		tokens.Unlock()
+
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: token, Path: "/"})
+		respond(w, http.StatusOK, "application/json", map[string]interface{}{
+			"access_token": token,
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}
+}
+
+func logout() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer close(w, r)
+		log.Info().Str("handler", "logout").Str("Authorization", r.Header.Get("Authorization")).Send()
+		respond(w, http.StatusNoContent, "text/html", nil)
+	}
+}
+
+func oauthToken() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer close(w, r)
+		r.ParseForm()
+		id, secret, ok := r.BasicAuth()
+		if !ok {
+			id = r.PostForm.Get("client_id")
+		}
+		grant := r.PostForm.Get("grant_type")
+		log.Info().Str("handler", "oauth/token").Str("grant_type", grant).Str("client_id", id).Str("scope", r.PostForm.Get("scope")).Send()
+
+		if id != "post-it" || (ok && secret != "s3cret") || (grant == "password" && r.PostForm.Get("password") != "hunter2") {
+			respond(w, http.StatusUnauthorized, "application/json", map[string]string{"error": "invalid_client"})
+			return
+		}
+		if grant != "client_credentials" && grant != "password" {
+			respond(w, http.StatusBadRequest, "application/json", map[string]string{"error": "unsupported_grant_type"})
+			return
+		}
+
+		token := strconv.FormatInt(rand.Int63(), 36)
+		tokens.Lock()
+		tokens.uses[token] = 3
+		tokens.Unlock()
+		respond(w, http.StatusOK, "application/json", map[string]interface{}{
+			"access_token": token,
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}
+}
+
+// signed verifies the default HMAC-SHA256 signature of post-it, keyed with s3cret.
+func signed() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer close(w, r)
+		body, _ := ioutil.ReadAll(r.Body)
+		hash := sha256.Sum256(body)
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write([]byte(strings.Join([]string{r.Method, r.URL.EscapedPath(), r.Header.Get("X-Timestamp"), hex.EncodeToString(hash[:])}, "\n")))
+		expected := hex.EncodeToString(mac.Sum(nil))
+		log.Info().Str("handler", "signed").Str("signature", r.Header.Get("X-Signature")).Str("expected", expected).Send()
+
+		if !hmac.Equal([]byte(r.Header.Get("X-Signature")), []byte(expected)) {
+			respond(w, http.StatusUnauthorized, "text/html", http.StatusText(http.StatusUnauthorized))
+			return
+		}
+		respond(w, http.StatusOK, "application/json", map[string]string{"id": mux.Vars(r)["id"]})
+	}
+}
+
+// cookies counts the visits of a client in a cookie and echoes the cookies it sent.
+func cookies() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer close(w, r)
+		received := make(map[string]string)
+		for _, c := range r.Cookies() {
+			received[c.Name] = c.Value
+		}
+		visits, _ := strconv.Atoi(received["visits"])
+		log.Info().Str("handler", "cookies").Int("visits", visits).Send()
+
+		http.SetCookie(w, &http.Cookie{Name: "visits", Value: strconv.Itoa(visits + 1), Path: "/", MaxAge: 3600})
+		respond(w, http.StatusOK, "application/json", received)
+	}
+}
+
+func basic() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer close(w, r)
+		user, password, ok := r.BasicAuth()
+		log.Info().Str("handler", "basic").Str("user", user).Send()
+		if !ok || user != "admin" || password != "hunter2" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="post-it"`)
+			respond(w, http.StatusUnauthorized, "text/html", http.StatusText(http.StatusUnauthorized))
+			return
+		}
+		respond(w, http.StatusOK, "application/json", map[string]string{"id": mux.Vars(r)["id"]})
+	}
+}
+
+// nonces maps issued digest nonces to the nonce counts seen. Nonces go stale
+// after 5 requests.
+var nonces = struct {
+	sync.Mutex
+	seen map[string]map[string]bool
+}{seen: make(map[string]map[string]bool)}
+
+// digest checks Digest authentication of admin:hunter2, with the algorithm
+// of the algorithm query parameter (default MD5).
+func digest() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer close(w, r)
+		algorithm := r.URL.Query().Get("algorithm")
+		if algorithm == "" {
+			algorithm = "MD5"
+		}
+		h := func(s string) string {
+			if algorithm == "SHA-256" {
+				sum := sha256.Sum256([]byte(s))
+				return hex.EncodeToString(sum[:])
+			}
+			sum := md5.Sum([]byte(s))
+			return hex.EncodeToString(sum[:])
+		}
+		challenge := func(stale bool) {
+			nonce := strconv.FormatInt(rand.Int63(), 36)
+			nonces.Lock()
+			nonces.seen[nonce] = make(map[string]bool)
+			nonces.Unlock()
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Digest realm="post-it", qop="auth", algorithm=%s, nonce="%s", opaque="abc", stale=%t`, algorithm, nonce, stale))
+			respond(w, http.StatusUnauthorized, "text/html", http.StatusText(http.StatusUnauthorized))
+		}
+
+		params := make(map[string]string)
+		authorization := r.Header.Get("Authorization")
+		for _, part := range strings.Split(strings.TrimPrefix(authorization, "Digest "), ",") {
+			kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+			if len(kv) == 2 {
+				params[kv[0]] = strings.Trim(kv[1], `"`)
+			}
+		}
+		log.Info().Str("handler", "digest").Str("nonce", params["nonce"]).Str("nc", params["nc"]).Send()
+		if !strings.HasPrefix(authorization, "Digest ") {
+			challenge(false)
+			return
+		}
+
+		nonces.Lock()
+		seen, ok := nonces.seen[params["nonce"]]
+		replayed := ok && seen[params["nc"]]
+		if ok && !replayed {
+			seen[params["nc"]] = true
+		}
+		nonces.Unlock()
+		if !ok || len(seen) > 5 {
+			challenge(true)
+			return
+		}
+
+		ha1 := h("admin:post-it:hunter2")
+		ha2 := h(r.Method + ":" + params["uri"])
+		expected := h(strings.Join([]string{ha1, params["nonce"], params["nc"], params["cnonce"], params["qop"], ha2}, ":"))
+		if replayed || params["response"] != expected || params["uri"] != r.URL.RequestURI() {
+			challenge(false)
+			return
+		}
+		respond(w, http.StatusOK, "application/json", map[string]string{"id": mux.Vars(r)["id"]})
+	}
+}
+
+func private() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer close(w, r)
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		log.Info().Str("handler", "private").Str("token", token).Send()
+
+		tokens.Lock()
+		uses := tokens.uses[token]
+		if uses > 0 {
+			tokens.uses[token] = uses - 1
+		}
+		tokens.Unlock()
+
+		if uses == 0 {
+			respond(w, http.StatusUnauthorized, "text/html", http.StatusText(http.StatusUnauthorized))
+			return
+		}
+		respond(w, http.StatusOK, "application/json", map[string]string{"id": mux.Vars(r)["id"]})
+	}
+}
+
 func respond(w http.ResponseWriter, code int, contentType string, payload interface{}) {
 	body, _ := marshal(contentType, payload)
 	if contentType == "" {